@@ -0,0 +1,701 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	sessionCookie = "acnh_session"
+	// csrfCookie holds the session's CSRF token where the page's scripts can
+	// read it, so they can send it back in csrfHeader or csrfField.
+	csrfCookie = "acnh_csrf"
+	csrfHeader = "X-CSRF-Token"
+	csrfField  = "csrf_token"
+
+	sessionLength    = 30 * 24 * time.Hour
+	apiTokenLength   = 365 * 24 * time.Hour
+	loginTokenLength = 15 * time.Minute
+	minPasswordLen   = 8
+)
+
+var (
+	errUsernameTaken     = errors.New("that username is taken")
+	errAlreadyRegistered = errors.New("you already have an account")
+	errBadLogin          = errors.New("wrong username or password")
+	errBadLoginToken     = errors.New("that login link is no good; it may have expired or been used already")
+	errShortPassword     = errors.New("passwords need to be at least 8 characters")
+	errLastAdmin         = errors.New("there has to be at least one admin")
+	errUnknownUser       = errors.New("no such user")
+	errBadAPIToken       = errors.New("that token is no good; it may have expired, so make a new one on the account page")
+)
+
+// dummyHash is checked against when logging in as somebody who doesn't
+// exist, so that takes as long as a wrong password does and doesn't give away
+// who has an account.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not anybody's password"), bcrypt.DefaultCost)
+
+// Session is one logged in browser, or a token for acnhctl, which comes in
+// an Authorization header instead of a cookie.
+type Session struct {
+	Expires time.Time `json:"expires"`
+	CSRF    string    `json:"csrf"`
+}
+
+type loginToken struct {
+	userID  string
+	expires time.Time
+}
+
+// Account is a registered user, as admins see them.
+type Account struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Admin    bool   `json:"admin"`
+	Islands  int    `json:"islands"`
+}
+
+// newToken returns a random token, and the hash it's stored under.
+func newToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// registered is whether the user ID belongs to an account.
+func (s *Store) registered(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	return ok && u.Username != ""
+}
+
+// account returns the ID of the user with the given username.  The lock must
+// be held.
+func (s *Store) account(username string) (string, *User, bool) {
+	for id, u := range s.users {
+		if u.Username != "" && strings.EqualFold(u.Username, username) {
+			return id, u, true
+		}
+	}
+	return "", nil, false
+}
+
+// admins counts the admins.  The lock must be held.
+func (s *Store) admins() int {
+	n := 0
+	for _, u := range s.users {
+		if u.Admin {
+			n++
+		}
+	}
+	return n
+}
+
+// Register turns an anonymous user into an account, keeping everything
+// they've tracked so far.  Without a password they can only log in with
+// links.  Nobody starts out an admin; see makeAdmins.
+func (s *Store) Register(userID, username, password string) error {
+	if password != "" && len(password) < minPasswordLen {
+		return errShortPassword
+	}
+	var hash []byte
+	if password != "" {
+		var err error
+		hash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, _, ok := s.account(username); ok {
+		return errUsernameTaken
+	}
+	u := s.user(userID)
+	if u.Username != "" {
+		return errAlreadyRegistered
+	}
+	u.Username = username
+	u.PasswordHash = hash
+	return s.save()
+}
+
+// makeAdmins makes the accounts with the given usernames admins, for when
+// there aren't any yet: the server makes the ones in ACNH_ADMINS admins when
+// it starts.  It returns the usernames nobody has registered.
+func (s *Store) makeAdmins(usernames []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var missing []string
+	changed := false
+	for _, username := range usernames {
+		_, u, ok := s.account(username)
+		if !ok {
+			missing = append(missing, username)
+			continue
+		}
+		if !u.Admin {
+			u.Admin = true
+			changed = true
+		}
+	}
+	if !changed {
+		return missing, nil
+	}
+	return missing, s.save()
+}
+
+// adminUsernames splits a comma separated list of usernames, like
+// ACNH_ADMINS.
+func adminUsernames(list string) []string {
+	var usernames []string
+	for _, username := range strings.Split(list, ",") {
+		if username = strings.TrimSpace(username); username != "" {
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
+
+// Login checks a username and password, returning the user's ID.
+func (s *Store) Login(username, password string) (string, error) {
+	s.mu.Lock()
+	id, u, ok := s.account(username)
+	var hash []byte
+	if ok {
+		hash = u.PasswordHash
+	}
+	s.mu.Unlock()
+	if len(hash) == 0 {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return "", errBadLogin
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		return "", errBadLogin
+	}
+	return id, nil
+}
+
+// LoginToken makes a one-time login token for the user, or returns false if
+// there's nobody by that name.
+func (s *Store) LoginToken(username string) (string, bool, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, _, ok := s.account(username)
+	if !ok {
+		return "", false, nil
+	}
+	if s.tokens == nil {
+		s.tokens = make(map[string]loginToken)
+	}
+	now := time.Now()
+	for h, t := range s.tokens {
+		if now.After(t.expires) {
+			delete(s.tokens, h)
+		}
+	}
+	s.tokens[hash] = loginToken{userID: id, expires: now.Add(loginTokenLength)}
+	return token, true, nil
+}
+
+// RedeemLoginToken uses up a login token, returning the ID of the user it
+// was for.
+func (s *Store) RedeemLoginToken(token string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hash := hashToken(token)
+	t, ok := s.tokens[hash]
+	if !ok {
+		return "", errBadLoginToken
+	}
+	delete(s.tokens, hash)
+	if time.Now().After(t.expires) {
+		return "", errBadLoginToken
+	}
+	if _, ok := s.users[t.userID]; !ok {
+		return "", errBadLoginToken
+	}
+	return t.userID, nil
+}
+
+// StartSession logs a browser in as the user, returning the session token
+// for its cookie.
+func (s *Store) StartSession(userID string) (string, Session, error) {
+	return s.startSession(userID, sessionLength)
+}
+
+// NewAPIToken starts a long session for acnhctl and returns its token.  Like
+// any other session, only its hash is kept.
+func (s *Store) NewAPIToken(userID string) (string, error) {
+	token, _, err := s.startSession(userID, apiTokenLength)
+	return token, err
+}
+
+func (s *Store) startSession(userID string, length time.Duration) (string, Session, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", Session{}, err
+	}
+	csrf, _, err := newToken()
+	if err != nil {
+		return "", Session{}, err
+	}
+	session := Session{Expires: time.Now().Add(length), CSRF: csrf}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.user(userID)
+	if u.Sessions == nil {
+		u.Sessions = make(map[string]Session)
+	}
+	for h, old := range u.Sessions {
+		if time.Now().After(old.Expires) {
+			delete(u.Sessions, h)
+		}
+	}
+	u.Sessions[hash] = session
+	return token, session, s.save()
+}
+
+// EndSession logs out whoever has the session token.
+func (s *Store) EndSession(token string) error {
+	hash := hashToken(token)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if _, ok := u.Sessions[hash]; ok {
+			delete(u.Sessions, hash)
+			return s.save()
+		}
+	}
+	return nil
+}
+
+// sessionToken returns the request's session cookie, or failing that the
+// bearer token in its Authorization header, and which one it was.
+func sessionToken(r *http.Request) (token string, bearer bool) {
+	if c, err := r.Cookie(sessionCookie); err == nil && c.Value != "" {
+		return c.Value, false
+	}
+	const prefix = "Bearer "
+	if h := r.Header.Get("Authorization"); len(h) > len(prefix) && strings.EqualFold(h[:len(prefix)], prefix) {
+		return strings.TrimSpace(h[len(prefix):]), true
+	}
+	return "", false
+}
+
+// sessionFor returns the user the request's session token logs in as, if
+// it has a session that hasn't expired.
+func (s *Store) sessionFor(r *http.Request) (string, Session, bool) {
+	token, _ := sessionToken(r)
+	if token == "" {
+		return "", Session{}, false
+	}
+	hash := hashToken(token)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, u := range s.users {
+		if session, ok := u.Sessions[hash]; ok && time.Now().Before(session.Expires) {
+			return id, session, true
+		}
+	}
+	return "", Session{}, false
+}
+
+// CurrentAccount returns the account the request is logged in to, if any.
+func (s *Store) CurrentAccount(r *http.Request) (Account, bool) {
+	id, _, ok := s.sessionFor(r)
+	if !ok {
+		return Account{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return Account{}, false
+	}
+	return Account{ID: id, Username: u.Username, Admin: u.Admin, Islands: len(u.Islands)}, true
+}
+
+// Accounts returns everybody who's registered, by username.
+func (s *Store) Accounts() []Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	accounts := []Account{}
+	for id, u := range s.users {
+		if u.Username != "" {
+			accounts = append(accounts, Account{ID: id, Username: u.Username, Admin: u.Admin, Islands: len(u.Islands)})
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Username < accounts[j].Username
+	})
+	return accounts
+}
+
+// SetAdmin makes an account an admin or takes it away, as long as that
+// doesn't leave nobody in charge.
+func (s *Store) SetAdmin(userID string, admin bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok || u.Username == "" {
+		return errUnknownUser
+	}
+	if u.Admin && !admin && s.admins() <= 1 {
+		return errLastAdmin
+	}
+	u.Admin = admin
+	return s.save()
+}
+
+// DeleteUser deletes a user and everything they've tracked.
+func (s *Store) DeleteUser(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	if !ok {
+		return errUnknownUser
+	}
+	if u.Admin && s.admins() <= 1 {
+		return errLastAdmin
+	}
+	delete(s.users, userID)
+	s.leaveGroups(userID, "")
+	return s.save()
+}
+
+// Export returns the whole store as JSON, without anybody's password hash,
+// sessions, calendar token or webhook secrets.
+func (s *Store) Export() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := storeFile{Users: make(map[string]*User, len(s.users)), Groups: s.groups}
+	for id, u := range s.users {
+		c := *u
+		c.PasswordHash = nil
+		c.Sessions = nil
+		c.CalendarTokenHash = ""
+		c.Islands = make(map[string]*Island, len(u.Islands))
+		for islandID, i := range u.Islands {
+			ic := *i
+			ic.Webhooks = make([]Webhook, len(i.Webhooks))
+			for n, hook := range i.Webhooks {
+				hook.Secret = ""
+				ic.Webhooks[n] = hook
+			}
+			c.Islands[islandID] = &ic
+		}
+		f.Users[id] = &c
+	}
+	return json.Marshal(f)
+}
+
+// logIn starts a session for the user and hands the browser its cookies.
+func logIn(w http.ResponseWriter, store *Store, userID string) error {
+	token, session, err := store.StartSession(userID)
+	if err != nil {
+		return err
+	}
+	maxAge := int(sessionLength / time.Second)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    session.CSRF,
+		Path:     "/",
+		MaxAge:   maxAge,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// logOut ends the request's session and clears its cookies.
+func logOut(w http.ResponseWriter, r *http.Request, store *Store) error {
+	for _, name := range []string{sessionCookie, csrfCookie, userCookie} {
+		http.SetCookie(w, &http.Cookie{Name: name, Path: "/", MaxAge: -1})
+	}
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	return store.EndSession(c.Value)
+}
+
+// csrfExempt are the paths other sites are meant to post to.  They check
+// signatures instead of cookies, so there's nothing to forge.
+var csrfExempt = []string{"/api/slash/"}
+
+// sameOrigin reports whether the request came from one of our own pages, or
+// at least not from some other site's: browsers say where a post comes from
+// in Origin, or failing that Referer.  Requests with neither aren't from a
+// browser, and can't be riding along on somebody's cookies.
+func sameOrigin(r *http.Request) bool {
+	from := r.Header.Get("Origin")
+	if from == "" {
+		from = r.Header.Get("Referer")
+	}
+	if from == "" {
+		return true
+	}
+	u, err := url.Parse(from)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// csrfProtect turns away anything but GETs that another site posted, so
+// nobody can be logged in or registered behind their back, except to the
+// paths in csrfExempt.  With a logged in session they also have to carry the
+// session's CSRF token, in the X-CSRF-Token header or a csrf_token form
+// field, unless the session came in an Authorization header: browsers don't
+// send those by themselves, so there's nothing to forge.
+func csrfProtect(store *Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, path := range csrfExempt {
+			if strings.HasPrefix(r.URL.Path, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if !sameOrigin(r) {
+			http.Error(w, "cross-site request", http.StatusForbidden)
+			return
+		}
+		if _, bearer := sessionToken(r); bearer {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, session, ok := store.sessionFor(r); ok {
+			token := r.Header.Get(csrfHeader)
+			if token == "" {
+				token = r.PostFormValue(csrfField)
+			}
+			if subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRF)) != 1 {
+				http.Error(w, "missing or wrong CSRF token", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkAPIToken turns away requests with a bearer token that doesn't log in
+// as anybody, rather than letting them carry on as somebody new.
+func checkAPIToken(store *Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, bearer := sessionToken(r); bearer {
+			if _, _, ok := store.sessionFor(r); !ok {
+				http.Error(w, errBadAPIToken.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAdmin only lets logged in admins through to next.
+func requireAdmin(store *Store, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		account, ok := store.CurrentAccount(r)
+		if !ok {
+			http.Error(w, "log in first", http.StatusUnauthorized)
+			return
+		}
+		if !account.Admin {
+			http.Error(w, "admins only", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+type AccountPage struct {
+	Account  Account
+	LoggedIn bool
+	Message  string
+	Error    string
+	// APIToken is a new token for acnhctl, only shown right after it's made.
+	APIToken string
+}
+
+// accountHandler shows who's logged in, and lets people register, log in
+// with a password or a link, make a token for acnhctl, and log out.
+// Everything posts back here.
+func accountHandler(languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		page := AccountPage{}
+		status := http.StatusOK
+		if r.Method == http.MethodPost {
+			username := strings.TrimSpace(r.FormValue("username"))
+			password := r.FormValue("password")
+			var err error
+			switch r.FormValue("action") {
+			case "register":
+				if username == "" {
+					err = errors.New("pick a username")
+					break
+				}
+				if err = store.Register(id.User, username, password); err == nil {
+					err = logIn(w, store, id.User)
+				}
+			case "login":
+				var id string
+				if id, err = store.Login(username, password); err == nil {
+					err = logIn(w, store, id)
+				}
+			case "link":
+				var token string
+				var ok bool
+				token, ok, err = store.LoginToken(username)
+				if err == nil {
+					if ok {
+						logger.Log("login link", "username", username, "path", "/login/token?token="+token)
+					}
+					page.Message = "If there's an account called " + username + ", a login link has been written to the server log."
+				}
+			case "api_token":
+				account, ok := store.CurrentAccount(r)
+				if !ok {
+					err = errors.New("log in first")
+					break
+				}
+				if page.APIToken, err = store.NewAPIToken(account.ID); err == nil {
+					page.Message = "Here's your token for acnhctl's -token flag.  It won't be shown again."
+				}
+			case "logout":
+				err = logOut(w, r, store)
+			default:
+				err = errors.New("unknown action")
+			}
+			if err == nil && page.Message == "" {
+				http.Redirect(w, r, "/account", http.StatusSeeOther)
+				return
+			}
+			if err != nil {
+				if !errors.Is(err, errBadLogin) && !errors.Is(err, errUsernameTaken) && !errors.Is(err, errAlreadyRegistered) && !errors.Is(err, errShortPassword) {
+					logger.Log("failed account action", "action", r.FormValue("action"), "error", err)
+				}
+				status = http.StatusBadRequest
+				page.Error = err.Error()
+			}
+		}
+		page.Account, page.LoggedIn = store.CurrentAccount(r)
+		if err := render(w, r, languages, store, id, tmpl, status, page); err != nil {
+			logger.Log("failed rendering account", "error", err)
+		}
+	}
+}
+
+// loginTokenHandler logs in with the one-time token from a login link.
+func loginTokenHandler(store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := store.RedeemLoginToken(r.URL.Query().Get("token"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err := logIn(w, store, id); err != nil {
+			logger.Log("failed starting session", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+// accountAPIHandler says who's logged in.
+func accountAPIHandler(store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		account, ok := store.CurrentAccount(r)
+		writeJSON(w, struct {
+			LoggedIn bool   `json:"logged_in"`
+			Username string `json:"username,omitempty"`
+			Admin    bool   `json:"admin"`
+		}{ok, account.Username, account.Admin}, logger)
+	}
+}
+
+// adminUsersHandler lists accounts on GET, makes the one in "id" an admin
+// or not on POST (with "admin" true or false), and deletes it on DELETE.
+func adminUsersHandler(store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, store.Accounts(), logger)
+			return
+		case http.MethodPost:
+			err = store.SetAdmin(r.FormValue("id"), r.FormValue("admin") == "true")
+		case http.MethodDelete:
+			err = store.DeleteUser(r.FormValue("id"))
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			if errors.Is(err, errUnknownUser) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, errLastAdmin) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			logger.Log("failed updating user", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// adminExportHandler downloads everybody's data.
+func adminExportHandler(store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := store.Export()
+		if err != nil {
+			logger.Log("failed exporting store", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="acnh-export.json"`)
+		w.Write(b)
+	}
+}
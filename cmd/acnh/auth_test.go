@@ -0,0 +1,242 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tempStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "acnh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := loadStore(filepath.Join(dir, "store.json"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+
+	if err := store.Register("first", "Daisy", "short"); err != errShortPassword {
+		t.Errorf("expected a short password to be refused, got %v", err)
+	}
+	if err := store.Register("first", "Daisy", "turnip prices"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Register("first", "Mae", "turnip prices"); err != errAlreadyRegistered {
+		t.Errorf("expected registering twice to fail, got %v", err)
+	}
+	if err := store.Register("second", "daisy", ""); err != errUsernameTaken {
+		t.Errorf("expected usernames to be case insensitive, got %v", err)
+	}
+	if err := store.Register("second", "Mae", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, err := store.Login("daisy", "turnip prices"); err != nil || id != "first" {
+		t.Errorf("expected to log in as first, got %q, %v", id, err)
+	}
+	if _, err := store.Login("Daisy", "stalk market"); err != errBadLogin {
+		t.Errorf("expected a wrong password to fail, got %v", err)
+	}
+	if _, err := store.Login("Mae", ""); err != errBadLogin {
+		t.Errorf("expected link-only accounts not to log in with a password, got %v", err)
+	}
+
+	if _, err := store.Login("nobody", "turnip prices"); err != errBadLogin {
+		t.Errorf("expected an unknown user not to log in, got %v", err)
+	}
+
+	accounts := store.Accounts()
+	if len(accounts) != 2 || accounts[0].Admin || accounts[1].Admin {
+		t.Fatalf("expected nobody to be an admin by registering, got %+v", accounts)
+	}
+	missing, err := store.makeAdmins(adminUsernames(" daisy, nobody ,"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 1 || missing[0] != "nobody" {
+		t.Errorf("expected nobody to be missing, got %v", missing)
+	}
+	accounts = store.Accounts()
+	if !accounts[0].Admin || accounts[1].Admin {
+		t.Fatalf("expected only Daisy to be an admin, got %+v", accounts)
+	}
+	if err := store.SetAdmin("first", false); err != errLastAdmin {
+		t.Errorf("expected removing the last admin to fail, got %v", err)
+	}
+}
+
+func TestLoginToken(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	if err := store.Register("someone", "Mae", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := store.LoginToken("nobody"); err != nil || ok {
+		t.Errorf("expected no token for an unknown user, got %v, %v", ok, err)
+	}
+	token, ok, err := store.LoginToken("mae")
+	if err != nil || !ok {
+		t.Fatalf("expected a token, got %v, %v", ok, err)
+	}
+	if id, err := store.RedeemLoginToken(token); err != nil || id != "someone" {
+		t.Errorf("expected the token to log in as someone, got %q, %v", id, err)
+	}
+	if _, err := store.RedeemLoginToken(token); err != errBadLoginToken {
+		t.Errorf("expected tokens to only work once, got %v", err)
+	}
+}
+
+func TestSessionsAndCSRF(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	if err := store.Register("someone", "Mae", ""); err != nil {
+		t.Fatal(err)
+	}
+	token, session, err := store.StartSession("someone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotUser string
+	h := csrfProtect(store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser = store.userID(w, r)
+	}))
+	post := func(form url.Values, header string) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/donations", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(&http.Cookie{Name: sessionCookie, Value: token})
+		if header != "" {
+			r.Header.Set(csrfHeader, header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := post(url.Values{}, ""); code != http.StatusForbidden {
+		t.Errorf("expected a post without a token to be forbidden, got %d", code)
+	}
+	if code := post(url.Values{}, "wrong"); code != http.StatusForbidden {
+		t.Errorf("expected a post with the wrong token to be forbidden, got %d", code)
+	}
+	if code := post(url.Values{}, session.CSRF); code != http.StatusOK || gotUser != "someone" {
+		t.Errorf("expected the header to let the post through as someone, got %d as %q", code, gotUser)
+	}
+	if code := post(url.Values{csrfField: {session.CSRF}}, ""); code != http.StatusOK {
+		t.Errorf("expected the form field to let the post through, got %d", code)
+	}
+
+	// Other sites can't post at all, logged in or not, except to the paths
+	// that check signatures instead.
+	tests := []struct {
+		Name     string
+		Path     string
+		Header   string
+		From     string
+		Expected int
+	}{
+		{"log in from another site", "/account", "Origin", "https://evil.example", http.StatusForbidden},
+		{"log in from a null origin", "/account", "Origin", "null", http.StatusForbidden},
+		{"log in linked from another site", "/account", "Referer", "https://evil.example/login", http.StatusForbidden},
+		{"log in from our own page", "/account", "Origin", "http://example.com", http.StatusOK},
+		{"log in linked from our own page", "/account", "Referer", "http://example.com/account", http.StatusOK},
+		{"log in without a browser", "/account", "", "", http.StatusOK},
+		{"slash command", "/api/slash/", "Origin", "https://hooks.slack.com", http.StatusOK},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, test.Path, strings.NewReader("action=login"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if test.Header != "" {
+			r.Header.Set(test.Header, test.From)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.Expected {
+			t.Errorf("failed test '%s': expected %d, got %d", test.Name, test.Expected, w.Code)
+		}
+	}
+
+	// Without a session the account's ID isn't good for anything, even if
+	// the cookie has it.
+	if err := store.EndSession(token); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: userCookie, Value: "someone"})
+	if id := store.userID(httptest.NewRecorder(), r); id == "someone" {
+		t.Errorf("expected a logged out cookie to get a new anonymous user")
+	}
+}
+
+func TestAPIToken(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	if err := store.Register("someone", "Mae", ""); err != nil {
+		t.Fatal(err)
+	}
+	token, err := store.NewAPIToken("someone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotUser string
+	h := csrfProtect(store, checkAPIToken(store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser = store.userID(w, r)
+	})))
+	tests := []struct {
+		Name          string
+		Authorization string
+		Expected      int
+		User          string
+	}{
+		{"token without a CSRF token", "Bearer " + token, http.StatusOK, "someone"},
+		{"lower case scheme", "bearer " + token, http.StatusOK, "someone"},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized, ""},
+		{"no token", "", http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		gotUser = ""
+		r := httptest.NewRequest(http.MethodPost, "/api/donations", strings.NewReader("category=bugs&id=ant&donated=true"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if test.Authorization != "" {
+			r.Header.Set("Authorization", test.Authorization)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.Expected {
+			t.Errorf("failed test '%s': expected %d, got %d", test.Name, test.Expected, w.Code)
+		}
+		if test.User != "" && gotUser != test.User {
+			t.Errorf("failed test '%s': expected to be %q, got %q", test.Name, test.User, gotUser)
+		}
+	}
+
+	// A browser's session cookie still needs its CSRF token, whatever else
+	// comes along with it.
+	cookie, _, err := store.StartSession("someone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/donations", nil)
+	r.AddCookie(&http.Cookie{Name: sessionCookie, Value: cookie})
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected a session cookie without a CSRF token to be forbidden, got %d", w.Code)
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+// leavingReminder is how long before a critter's last day calendar apps
+// should remind people.
+const leavingReminder = "-P3D"
+
+// CalendarQuery picks what goes in a calendar feed.  An empty Categories
+// means every kind of critter.  Daily adds an event for every stretch of the
+// day a critter's around, on every day it's in season.
+type CalendarQuery struct {
+	Categories []string
+	Undonated  bool
+	Daily      bool
+}
+
+func (q CalendarQuery) includes(category string) bool {
+	if len(q.Categories) == 0 {
+		return true
+	}
+	for _, c := range q.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCalendarQuery(q url.Values) (CalendarQuery, error) {
+	query := CalendarQuery{
+		Undonated: q.Get("undonated") == "true",
+		Daily:     q.Get("daily") == "true",
+	}
+	for _, c := range q["category"] {
+		for _, category := range strings.Split(c, ",") {
+			switch category {
+			case "bugs", "fishes", "sea_creatures":
+				query.Categories = append(query.Categories, category)
+			default:
+				return query, errors.New("category must be bugs, fishes or sea_creatures")
+			}
+		}
+	}
+	return query, nil
+}
+
+// calendar writes iCalendar content lines, folded and CRLF terminated.
+type calendar struct {
+	w   io.Writer
+	err error
+}
+
+func (c *calendar) line(name, value string) {
+	if c.err != nil {
+		return
+	}
+	l := name + ":" + value
+	var b strings.Builder
+	// Lines can't be longer than 75 octets; longer ones carry on after a
+	// CRLF and a space.
+	for max := 75; len(l) > max; max = 74 {
+		cut := max
+		for cut > 0 && !utf8.RuneStart(l[cut]) {
+			cut--
+		}
+		b.WriteString(l[:cut] + "\r\n ")
+		l = l[cut:]
+	}
+	b.WriteString(l + "\r\n")
+	_, c.err = io.WriteString(c.w, b.String())
+}
+
+// icalText escapes text values.
+func icalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func icalDate(t time.Time) string {
+	return t.Format("20060102")
+}
+
+func icalLocal(t time.Time) string {
+	return t.Format("20060102T150405")
+}
+
+func icalOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		s += fmt.Sprintf("%02d", seconds%60)
+	}
+	return s
+}
+
+// writeTimezone describes loc from the start of year through the end of the
+// next one, which is as far ahead as the feed needs to be right; calendar
+// apps refetch it long before then.
+func writeTimezone(c *calendar, loc *time.Location, year int) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	_, jan := start.Zone()
+	_, jul := time.Date(year, time.July, 1, 0, 0, 0, 0, loc).Zone()
+	standard := jan
+	if jul < jan {
+		standard = jul
+	}
+	observance := func(at time.Time, from, to int, name string) {
+		kind := "STANDARD"
+		if to > standard {
+			kind = "DAYLIGHT"
+		}
+		c.line("BEGIN", kind)
+		c.line("DTSTART", icalLocal(at))
+		c.line("TZOFFSETFROM", icalOffset(from))
+		c.line("TZOFFSETTO", icalOffset(to))
+		c.line("TZNAME", icalText(name))
+		c.line("END", kind)
+	}
+
+	c.line("BEGIN", "VTIMEZONE")
+	c.line("TZID", loc.String())
+	name, offset := start.Zone()
+	observance(start, offset, offset, name)
+	end := start.AddDate(2, 0, 0)
+	for t := start.Add(time.Hour); t.Before(end); t = t.Add(time.Hour) {
+		name, next := t.Zone()
+		if next == offset {
+			continue
+		}
+		// Onsets are in the local time from before the change.
+		observance(t.In(time.FixedZone("", offset)), offset, next, name)
+		offset = next
+	}
+	c.line("END", "VTIMEZONE")
+}
+
+// writeCalendar writes a feed with events for when each critter arrives and
+// leaves every year, with a reminder before it goes, and, if the query asks,
+// for each stretch of the day it's around while it's in season.  critters
+// should already be in the island's hemisphere, and t in its time zone.
+func writeCalendar(w io.Writer, critters ACNH, donated map[string][]string, q CalendarQuery, t time.Time) error {
+	c := &calendar{w: w}
+	year := t.Year()
+	stamp := t.UTC().Format("20060102T150405Z")
+	tz := t.Location().String()
+
+	c.line("BEGIN", "VCALENDAR")
+	c.line("VERSION", "2.0")
+	c.line("PRODID", "-//acnh//Critter availability//EN")
+	c.line("CALSCALE", "GREGORIAN")
+	c.line("X-WR-CALNAME", "Animal Crossing critters")
+	if q.Daily {
+		writeTimezone(c, t.Location(), year)
+	}
+
+	done := donatedSets(donated)
+	for _, cr := range critters.critters() {
+		if !q.includes(cr.Category) || (q.Undonated && done[cr.Category][cr.ID]) {
+			continue
+		}
+		uid := cr.Category + "-" + cr.ID
+		seasons := season.Spans(func(m int) bool { return contains(cr.Months, m) }, 12)
+		if len(cr.Months) > 0 && len(cr.Months) < 12 {
+			for i, s := range seasons {
+				last := (s.Start + s.Length - 1) % 12
+				next := seasons[(i+1)%len(seasons)].Start
+				c.line("BEGIN", "VEVENT")
+				c.line("UID", fmt.Sprintf("%s-arrives-%d@acnh", uid, s.Start+1))
+				c.line("DTSTAMP", stamp)
+				c.line("DTSTART;VALUE=DATE", icalDate(time.Date(year, time.Month(s.Start+1), 1, 0, 0, 0, 0, time.UTC)))
+				c.line("RRULE", "FREQ=YEARLY")
+				c.line("SUMMARY", icalText(cr.Name+" arrives"))
+				c.line("LOCATION", icalText(cr.Location))
+				c.line("TRANSP", "TRANSPARENT")
+				c.line("END", "VEVENT")
+
+				c.line("BEGIN", "VEVENT")
+				c.line("UID", fmt.Sprintf("%s-leaves-%d@acnh", uid, last+1))
+				c.line("DTSTAMP", stamp)
+				c.line("DTSTART;VALUE=DATE", icalDate(time.Date(year, time.Month(last+2), 0, 0, 0, 0, 0, time.UTC)))
+				c.line("RRULE", "FREQ=YEARLY;BYMONTH="+strconv.Itoa(last+1)+";BYMONTHDAY=-1")
+				c.line("SUMMARY", icalText("Last day for "+cr.Name))
+				c.line("LOCATION", icalText(cr.Location))
+				c.line("DESCRIPTION", icalText(fmt.Sprintf("%s sells for %d bells and won't be back until %s.", cr.Name, cr.Price, time.Month(next+1))))
+				c.line("TRANSP", "TRANSPARENT")
+				c.line("BEGIN", "VALARM")
+				c.line("ACTION", "DISPLAY")
+				c.line("DESCRIPTION", icalText(cr.Name+" is leaving soon"))
+				c.line("TRIGGER", leavingReminder)
+				c.line("END", "VALARM")
+				c.line("END", "VEVENT")
+			}
+		}
+
+		if !q.Daily || len(cr.Months) == 0 || len(cr.Hours) == 0 || len(cr.Hours) >= 24 {
+			continue
+		}
+		months := make([]string, len(cr.Months))
+		first := 12
+		for i, m := range cr.Months {
+			months[i] = strconv.Itoa(m + 1)
+			if m < first {
+				first = m
+			}
+		}
+		rule := "FREQ=DAILY"
+		if len(cr.Months) < 12 {
+			rule += ";BYMONTH=" + strings.Join(months, ",")
+		}
+		for _, h := range season.Spans(func(h int) bool { return cr.HourMap[h] }, 24) {
+			c.line("BEGIN", "VEVENT")
+			c.line("UID", fmt.Sprintf("%s-daily-%d@acnh", uid, h.Start))
+			c.line("DTSTAMP", stamp)
+			c.line("DTSTART;TZID="+tz, icalLocal(time.Date(year, time.Month(first+1), 1, h.Start, 0, 0, 0, t.Location())))
+			c.line("DURATION", "PT"+strconv.Itoa(h.Length)+"H")
+			c.line("RRULE", rule)
+			c.line("SUMMARY", icalText(cr.Name+" is out"))
+			c.line("LOCATION", icalText(cr.Location))
+			c.line("TRANSP", "TRANSPARENT")
+			c.line("END", "VEVENT")
+		}
+	}
+	c.line("END", "VCALENDAR")
+	return c.err
+}
+
+// NewCalendarToken makes a new secret that lets calendar apps fetch the
+// user's feed without their cookie, and stops the old one working.  Only its
+// hash is kept, so this is the only time it can be shown.
+func (s *Store) NewCalendarToken(userID string) (string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user(userID).CalendarTokenHash = hash
+	return token, s.save()
+}
+
+// HasCalendarToken reports whether the user has made a calendar token.
+func (s *Store) HasCalendarToken(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[userID]
+	return ok && u.CalendarTokenHash != ""
+}
+
+// CalendarUser returns the ID of the user whose calendar token it is.
+func (s *Store) CalendarUser(token string) (string, bool) {
+	hash := []byte(hashToken(token))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, u := range s.users {
+		if u.CalendarTokenHash != "" && subtle.ConstantTimeCompare([]byte(u.CalendarTokenHash), hash) == 1 {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// calendarHandler serves the iCalendar feed for the user's island.  Calendar
+// apps don't have the user's cookie, so they pass "token" instead, and
+// "profile" for which island.  "category" (repeatable), "undonated" and
+// "daily" filter it.
+func calendarHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var id Profile
+		if token := r.FormValue("token"); token != "" {
+			user, ok := store.CalendarUser(token)
+			if !ok {
+				http.Error(w, "no such calendar", http.StatusNotFound)
+				return
+			}
+			id = Profile{User: user, Island: r.FormValue("profile")}
+		} else {
+			id = store.profile(w, r)
+		}
+		q, err := parseCalendarQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		t, err := store.Now(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			logger.Log("failed loading timezone data", "error", err)
+			return
+		}
+
+		c := critters.forHemisphere(hemisphereFor(r, store, id))
+		var b bytes.Buffer
+		if err := writeCalendar(&b, c, store.AllDonated(id), q, t); err != nil {
+			logger.Log("failed writing calendar", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", `inline; filename="acnh.ics"`)
+		w.Write(b.Bytes())
+	}
+}
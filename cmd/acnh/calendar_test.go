@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarLineFolding(t *testing.T) {
+	var b bytes.Buffer
+	c := &calendar{w: &b}
+	c.line("DESCRIPTION", strings.Repeat("é", 60))
+	for _, l := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("expected lines of at most 75 octets, got %d", len(l))
+		}
+	}
+	if unfolded := strings.Replace(b.String(), "\r\n ", "", -1); unfolded != "DESCRIPTION:"+strings.Repeat("é", 60)+"\r\n" {
+		t.Errorf("expected folding not to change the value, got %q", unfolded)
+	}
+}
+
+func TestWriteCalendar(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no timezone data")
+	}
+	critters := ACNH{
+		Bugs: []Bug{
+			{ID: "ant", Name: "Ant", Months: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, Hours: []int{}},
+			{ID: "tarantula", Name: "Tarantula", Months: []int{0, 1, 2, 3, 10, 11}, Hours: []int{19, 20, 21, 22, 23, 0, 1, 2, 3}, Location: "On the ground"},
+		},
+		Fishes: []Fish{{ID: "koi", Name: "Koi", Months: []int{0}}},
+	}
+	for i := range critters.Bugs {
+		setHourMap(&critters.Bugs[i])
+	}
+	now := time.Date(2020, time.June, 1, 12, 0, 0, 0, loc)
+
+	var b bytes.Buffer
+	q := CalendarQuery{Categories: []string{"bugs"}, Daily: true}
+	if err := writeCalendar(&b, critters, nil, q, now); err != nil {
+		t.Fatal(err)
+	}
+	ics := b.String()
+	for _, want := range []string{
+		"UID:bugs-tarantula-arrives-11@acnh\r\nDTSTAMP:20200601T160000Z\r\nDTSTART;VALUE=DATE:20201101\r\n",
+		"DTSTART;VALUE=DATE:20200430\r\nRRULE:FREQ=YEARLY;BYMONTH=4;BYMONTHDAY=-1\r\n",
+		"won't be back until November.",
+		"DTSTART;TZID=America/New_York:20200101T190000\r\nDURATION:PT9H\r\nRRULE:FREQ=DAILY;BYMONTH=1,2,3,4,11,12\r\n",
+		"TZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("expected calendar to contain %q", want)
+		}
+	}
+	if strings.Contains(ics, "Ant") || strings.Contains(ics, "Koi") {
+		t.Errorf("expected year-round critters and other categories to be left out")
+	}
+
+	b.Reset()
+	critters.Bugs[1].Name = "Giant tarantula"
+	if err := writeCalendar(&b, critters, nil, q, now); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "UID:bugs-tarantula-arrives-11@acnh") {
+		t.Errorf("expected UIDs to stay put when a critter is renamed")
+	}
+
+	b.Reset()
+	q = CalendarQuery{Undonated: true}
+	if err := writeCalendar(&b, critters, map[string][]string{"fishes": {"koi"}}, q, now); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "Koi") || strings.Contains(b.String(), "VTIMEZONE") {
+		t.Errorf("expected donated critters and the time zone to be left out")
+	}
+}
+
+func TestCalendarToken(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	if store.HasCalendarToken("daisy") {
+		t.Errorf("expected no calendar token before making one")
+	}
+	old, err := store.NewCalendarToken("daisy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := store.NewCalendarToken("daisy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.CalendarUser(old); ok {
+		t.Errorf("expected a new token to stop the old one working")
+	}
+	if id, ok := store.CalendarUser(token); !ok || id != "daisy" {
+		t.Errorf("expected the token to be daisy's, got %q, %v", id, ok)
+	}
+	if !store.HasCalendarToken("daisy") {
+		t.Errorf("expected daisy to have a calendar token")
+	}
+
+	saved, err := ioutil.ReadFile(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := store.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(saved), token) || strings.Contains(string(exported), hashToken(token)) {
+		t.Errorf("expected only the hash to be saved and neither to be exported")
+	}
+
+	// Tokens from before they were hashed keep working.
+	if err := ioutil.WriteFile(store.path, []byte(`{"users": {"mae": {"calendar_token": "legacy"}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := loadStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := reloaded.CalendarUser("legacy"); !ok || id != "mae" || reloaded.users["mae"].CalendarToken != "" {
+		t.Errorf("expected the legacy token to be hashed and still be mae's, got %q, %v", id, ok)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+type CatalogCategory struct {
+	ID    string        `json:"id"`
+	Name  string        `json:"name"`
+	Items []CatalogItem `json:"items"`
+}
+
+type CatalogItem struct {
+	ID                 string   `json:"id"`
+	Name               string   `json:"name"`
+	DIY                bool     `json:"diy"`
+	BuyPrice           Price    `json:"buy_price"`
+	SellPrice          Price    `json:"sell_price"`
+	MilesPrice         Price    `json:"miles_price"`
+	HHABase            int      `json:"hha_base"`
+	Colors             []string `json:"colors"`
+	Variants           []string `json:"variants,omitempty"`
+	Size               string   `json:"size,omitempty"`
+	Source             string   `json:"source"`
+	SourceNotes        string   `json:"source_notes,omitempty"`
+	VillagerEquippable bool     `json:"villager_equippable"`
+	Catalog            string   `json:"catalog"`
+	Aliases            []string `json:"aliases,omitempty"`
+	Acquired           bool     `json:"acquired"`
+}
+
+type PriceState int
+
+const (
+	Priced PriceState = iota
+	NotForSale
+	NotApplicable
+)
+
+// Price is an amount of bells (or miles), or "NFS"/"NA" when there isn't one.
+// In JSON it's either a plain number or one of those two strings.
+type Price struct {
+	State  PriceState
+	Amount int
+}
+
+func (p Price) String() string {
+	switch p.State {
+	case NotForSale:
+		return "NFS"
+	case NotApplicable:
+		return "NA"
+	}
+	return fmt.Sprintf("%d", p.Amount)
+}
+
+func (p Price) MarshalJSON() ([]byte, error) {
+	if p.State == Priced {
+		return json.Marshal(p.Amount)
+	}
+	return json.Marshal(p.String())
+}
+
+func (p *Price) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		switch s {
+		case "NFS":
+			*p = Price{State: NotForSale}
+		case "NA":
+			*p = Price{State: NotApplicable}
+		default:
+			return fmt.Errorf("unknown price state '%s'", s)
+		}
+		return nil
+	}
+	*p = Price{}
+	return json.Unmarshal(b, &p.Amount)
+}
+
+// CatalogSearch is what the catalog page can be narrowed down by.
+type CatalogSearch struct {
+	Query    string `json:"q,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Acquired is "yes", "no", or empty for both.
+	Acquired string `json:"acquired,omitempty"`
+}
+
+type CatalogPage struct {
+	Search     CatalogSearch
+	Categories []CatalogCategory
+	// AllCategories is every category, for the drop-down, regardless of
+	// what's been searched for.
+	AllCategories []CatalogCategory
+}
+
+// searchCatalog returns the categories and items matching search, with each
+// item's Acquired set for the given user.  Categories with nothing left in
+// them are dropped.
+func searchCatalog(catalog []CatalogCategory, search CatalogSearch, store *Store, id Profile) []CatalogCategory {
+	query := strings.ToLower(strings.TrimSpace(search.Query))
+	var results []CatalogCategory
+	for _, c := range catalog {
+		if search.Category != "" && search.Category != c.ID {
+			continue
+		}
+		acquired := store.Donated(id, c.ID)
+		category := CatalogCategory{ID: c.ID, Name: c.Name}
+		for _, item := range c.Items {
+			item.Acquired = acquired[item.ID]
+			if query != "" && !strings.Contains(strings.ToLower(item.Name), query) {
+				continue
+			}
+			if (search.Acquired == "yes" && !item.Acquired) || (search.Acquired == "no" && item.Acquired) {
+				continue
+			}
+			category.Items = append(category.Items, item)
+		}
+		if len(category.Items) > 0 {
+			results = append(results, category)
+		}
+	}
+	return results
+}
+
+func parseCatalogSearch(r *http.Request) CatalogSearch {
+	q := r.URL.Query()
+	return CatalogSearch{
+		Query:    q.Get("q"),
+		Category: q.Get("category"),
+		Acquired: q.Get("acquired"),
+	}
+}
+
+func catalogHandler(catalog []CatalogCategory, languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		search := parseCatalogSearch(r)
+		page := CatalogPage{
+			Search:        search,
+			Categories:    searchCatalog(catalog, search, store, id),
+			AllCategories: catalog,
+		}
+		if err := render(w, r, languages, store, id, tmpl, http.StatusOK, page); err != nil {
+			logger.Log("failed rendering catalog", "error", err)
+		}
+	}
+}
+
+func catalogAPIHandler(catalog []CatalogCategory, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, searchCatalog(catalog, parseCatalogSearch(r), store, store.profile(w, r)), logger)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+// critterPath is where a critter's own page is.
+func critterPath(category, name string) string {
+	return "/critter/" + category + "/" + url.PathEscape(name)
+}
+
+func (b Bug) Path() string {
+	return critterPath("bugs", b.Name)
+}
+
+func (f Fish) Path() string {
+	return critterPath("fishes", f.Name)
+}
+
+func (s SeaCreature) Path() string {
+	return critterPath("sea_creatures", s.Name)
+}
+
+func (c Critter) Path() string {
+	return critterPath(c.Category, c.Name)
+}
+
+// Path is the result's critter page, or empty if it isn't a critter.
+func (r SearchResult) Path() string {
+	switch r.Category {
+	case "bugs", "fishes", "sea_creatures":
+		return critterPath(r.Category, r.Name)
+	}
+	return ""
+}
+
+// Season is when a critter's around in one hemisphere.  Months has a flag
+// for each month, January first.
+type Season struct {
+	Hemisphere Hemisphere `json:"hemisphere"`
+	Months     []bool     `json:"months"`
+	Display    string     `json:"display"`
+}
+
+// CritterDetails is everything about one critter.  Timing and Next are for
+// the island's hemisphere and clock.
+type CritterDetails struct {
+	Critter
+	Speed      string         `json:"speed,omitempty"`
+	Hemisphere Hemisphere     `json:"current_hemisphere"`
+	Seasons    []Season       `json:"seasons"`
+	HoursShown []bool         `json:"hours_shown"`
+	HoursText  string         `json:"hours_display"`
+	Next       *season.Window `json:"next,omitempty"`
+	Donated    bool           `json:"donated"`
+}
+
+// findCritterByName returns the critter in the category with exactly that
+// name, ignoring case, or that used to have it, so old links still work.
+func findCritterByName(critters ACNH, category, name string) (Critter, bool) {
+	for _, c := range critters.critters() {
+		if c.Category != category {
+			continue
+		}
+		for _, n := range append([]string{c.Name}, c.Aliases...) {
+			if strings.EqualFold(n, name) {
+				return c, true
+			}
+		}
+	}
+	return Critter{}, false
+}
+
+// critterDetails puts together everything about a critter for the island's
+// hemisphere, at t on its clock.  critters is the data as loaded, which is
+// all northern.
+func critterDetails(critters ACNH, category, name string, h Hemisphere, donated map[string][]string, t time.Time) (CritterDetails, bool) {
+	north, ok := findCritterByName(critters, category, name)
+	if !ok {
+		return CritterDetails{}, false
+	}
+	c := north
+	c.Months = season.Shift(north.Months, h)
+	d := CritterDetails{Critter: c, Hemisphere: h, HoursText: season.DescribeHours(c.HourMap)}
+	for _, sc := range critters.SeaCreatures {
+		if category == "sea_creatures" && sc.Name == c.Name {
+			d.Speed = sc.Speed
+		}
+	}
+	for _, hemisphere := range season.Hemispheres {
+		months := season.Shift(north.Months, hemisphere)
+		s := Season{Hemisphere: hemisphere, Months: make([]bool, 12), Display: season.DescribeMonths(months)}
+		for _, m := range months {
+			s.Months[m] = true
+		}
+		d.Seasons = append(d.Seasons, s)
+	}
+	for hour := 0; hour < 24; hour++ {
+		d.HoursShown = append(d.HoursShown, c.HourMap[hour])
+	}
+	d.Timing = season.At(c.Months, c.HourMap, t)
+	d.Next = season.Next(c.Months, c.HourMap, t)
+	d.Donated = donatedSets(donated)[category][c.ID]
+	return d, true
+}
+
+// CritterPage is a critter's page, with headings for the month and hour grids.
+type CritterPage struct {
+	CritterDetails
+	MonthNames []string
+	Hours      []int
+}
+
+// critterRequest reads the category and name out of a /critter/ or
+// /api/critter/ path and works out the details for the user's island.
+func critterRequest(critters ACNH, store *Store, prefix string, w http.ResponseWriter, r *http.Request, logger Logger) (CritterDetails, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, prefix), "/", 2)
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return CritterDetails{}, false
+	}
+	id := store.profile(w, r)
+	t, err := store.Now(id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		logger.Log("failed loading timezone data", "error", err)
+		return CritterDetails{}, false
+	}
+	d, ok := critterDetails(critters, parts[0], parts[1], hemisphereFor(r, store, id), store.AllDonated(id), t)
+	if !ok {
+		http.Error(w, "no such critter", http.StatusNotFound)
+		return d, false
+	}
+	return d, true
+}
+
+// critterHandler shows one critter's page, at /critter/{category}/{name}.
+func critterHandler(critters ACNH, languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d, ok := critterRequest(critters, store, "/critter/", w, r, logger)
+		if !ok {
+			return
+		}
+		page := CritterPage{CritterDetails: d}
+		for m := time.January; m <= time.December; m++ {
+			page.MonthNames = append(page.MonthNames, m.String()[:3])
+		}
+		for h := 0; h < 24; h++ {
+			page.Hours = append(page.Hours, h)
+		}
+		if err := render(w, r, languages, store, store.profile(w, r), tmpl, http.StatusOK, page); err != nil {
+			logger.Log("failed rendering critter", "error", err)
+		}
+	}
+}
+
+func critterAPIHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d, ok := critterRequest(critters, store, "/api/critter/", w, r, logger); ok {
+			writeJSON(w, d, logger)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCritterDetails(t *testing.T) {
+	critters := slashCritters()
+	may := time.Date(2020, time.May, 4, 20, 0, 0, 0, time.UTC)
+	donated := map[string][]string{"bugs": {"tarantula"}}
+
+	d, ok := critterDetails(critters, "bugs", "tarantula", Southern, donated, may)
+	if !ok || d.Name != "Tarantula" || !d.Donated {
+		t.Fatalf("expected to find the donated tarantula, got %+v", d)
+	}
+	if len(d.Seasons) != 2 || d.Seasons[0].Display != "November to April" || d.Seasons[1].Display != "May to October" {
+		t.Errorf("expected seasons for both hemispheres, got %+v", d.Seasons)
+	}
+	if !d.Seasons[1].Months[4] || d.Seasons[0].Months[4] {
+		t.Errorf("expected May only in the south, got %+v", d.Seasons)
+	}
+	if !d.Timing.AvailableNow || d.Next == nil || !d.Next.Start.Equal(may) {
+		t.Errorf("expected the tarantula to be out now in the south, got %+v", d)
+	}
+	if !d.HoursShown[19] || d.HoursShown[12] || d.HoursText != "7PM to 4AM" {
+		t.Errorf("unexpected hours %v %q", d.HoursShown, d.HoursText)
+	}
+
+	d, _ = critterDetails(critters, "bugs", "Tarantula", Northern, nil, may)
+	if d.Timing.Status != StatusOutOfSeason || d.Next.Start.Month() != time.November || d.Donated {
+		t.Errorf("expected the tarantula to be out of season in the north until November, got %+v", d)
+	}
+	if _, ok := critterDetails(critters, "fishes", "Tarantula", Northern, nil, may); ok {
+		t.Errorf("expected the category to have to match")
+	}
+
+	critters.Bugs[0].Aliases = []string{"Tarantulla"}
+	if d, ok := critterDetails(critters, "bugs", "tarantulla", Northern, nil, may); !ok || d.Name != "Tarantula" {
+		t.Errorf("expected the tarantula under its old name, got %+v", d)
+	}
+}
+
+func TestCritterHandler(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	tmpl := template.Must(template.New("critter").Parse("{{ .Name }} {{ len .MonthNames }}"))
+	h := critterHandler(slashCritters(), Languages{"en": english}, store, tmpl, nopLogger{})
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, critterPath("fishes", "Coelacanth"), nil))
+	if w.Code != http.StatusOK || w.Body.String() != "Coelacanth 12" {
+		t.Errorf("expected the coelacanth's page, got %d: %q", w.Code, w.Body.String())
+	}
+	for _, path := range []string{"/critter/fishes/Nope", "/critter/fishes"} {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected %s not to exist, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+// feedMonths is how many month boundaries the feed goes back.
+const feedMonths = 12
+
+// MonthChange is what happens at the start of a month: which critters show
+// up that weren't around the month before, and which ones are around for
+// the last time until next season.
+type MonthChange struct {
+	Month    time.Time
+	Arriving []Critter
+	Leaving  []Critter
+}
+
+func (m MonthChange) Title() string {
+	return fmt.Sprintf("%s %d: %d arriving, %d leaving", m.Month.Month(), m.Month.Year(), len(m.Arriving), len(m.Leaving))
+}
+
+// monthChanges works out the changes at the start of t's month and the n-1
+// months before it, newest first.  critters should already be in the right
+// hemisphere.
+func monthChanges(critters ACNH, t time.Time, n int) []MonthChange {
+	all := critters.critters()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	var changes []MonthChange
+	for i := 0; i < n; i++ {
+		first := start.AddDate(0, -i, 0)
+		month := int(first.Month()) - 1
+		change := MonthChange{Month: first, Arriving: []Critter{}, Leaving: []Critter{}}
+		for _, c := range all {
+			if !contains(c.Months, month) {
+				continue
+			}
+			if !contains(c.Months, (month+11)%12) {
+				change.Arriving = append(change.Arriving, c)
+			}
+			if !contains(c.Months, (month+1)%12) {
+				change.Leaving = append(change.Leaving, c)
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Summary string      `xml:"summary"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// feedID makes a stable ID out of whatever identifies something in the
+// feed, so entries don't show up again as new when the feed's regenerated.
+func feedID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "/")))
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func critterNames(critters []Critter) string {
+	names := make([]string, len(critters))
+	for i, c := range critters {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// changeHTML lists what's arriving and leaving for the entry's content.
+func changeHTML(m MonthChange) string {
+	var b strings.Builder
+	for _, section := range []struct {
+		heading  string
+		critters []Critter
+	}{{"Arriving", m.Arriving}, {"Leaving after this month", m.Leaving}} {
+		if len(section.critters) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "<h3>%s</h3><ul>", section.heading)
+		for _, c := range section.critters {
+			fmt.Fprintf(&b, "<li>%s (%s, %d bells)</li>", html.EscapeString(c.Name), html.EscapeString(c.Location), c.Price)
+		}
+		b.WriteString("</ul>")
+	}
+	return b.String()
+}
+
+// atom builds the feed.  base is the server's URL, for links.
+func atom(changes []MonthChange, h Hemisphere, base string) atomFeed {
+	self := base + "/feed.atom?hemisphere=" + string(h)
+	feed := atomFeed{
+		Title:  "Animal Crossing critters coming and going (" + h.Name() + " hemisphere)",
+		ID:     feedID("acnh", "months", string(h)),
+		Author: atomPerson{Name: "acnh"},
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: base + "/?hemisphere=" + string(h), Rel: "alternate", Type: "text/html"},
+		},
+	}
+	for _, m := range changes {
+		updated := m.Month.Format(time.RFC3339)
+		if feed.Updated == "" {
+			feed.Updated = updated
+		}
+		summary := "Arriving: " + critterNames(m.Arriving) + ". Leaving after this month: " + critterNames(m.Leaving) + "."
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   m.Title(),
+			ID:      feedID("acnh", "months", string(h), m.Month.Format("2006-01")),
+			Updated: updated,
+			Links:   []atomLink{{Href: base + "/?hemisphere=" + string(h), Rel: "alternate", Type: "text/html"}},
+			Summary: summary,
+			Content: atomContent{Type: "html", Body: changeHTML(m)},
+		})
+	}
+	return feed
+}
+
+// baseURL is the URL the request came in on, minus the path.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// feedHandler serves an Atom feed with an entry for each month boundary.
+// Feed readers don't have cookies, so the hemisphere and time zone the
+// months change in come from "hemisphere" and "tz" rather than an island.
+func feedHandler(critters ACNH, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h := Northern
+		if v := r.FormValue("hemisphere"); v != "" {
+			var err error
+			if h, err = season.ParseHemisphere(v); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		tz := r.FormValue("tz")
+		if tz == "" {
+			tz = defaultTimezone
+		}
+		t, err := now(tz)
+		if err != nil {
+			http.Error(w, "unknown time zone: "+tz, http.StatusBadRequest)
+			return
+		}
+
+		feed := atom(monthChanges(critters.forHemisphere(h), t, feedMonths), h, baseURL(r))
+		var b bytes.Buffer
+		b.WriteString(xml.Header)
+		if err := xml.NewEncoder(&b).Encode(feed); err != nil {
+			logger.Log("failed writing feed", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		w.Write(b.Bytes())
+	}
+}
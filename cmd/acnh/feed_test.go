@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMonthChanges(t *testing.T) {
+	critters := ACNH{
+		Bugs: []Bug{
+			{Name: "Ant", Months: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+			{Name: "Tarantula", Months: []int{0, 1, 2, 3, 10, 11}},
+		},
+		Fishes: []Fish{{Name: "Koi", Months: []int{4}}},
+	}
+	now := time.Date(2020, time.May, 17, 12, 0, 0, 0, time.UTC)
+
+	changes := monthChanges(critters, now, 7)
+	if len(changes) != 7 || changes[0].Month.Month() != time.May || changes[6].Month.Month() != time.November || changes[6].Month.Year() != 2019 {
+		t.Fatalf("expected May back to last November, got %+v", changes)
+	}
+	if names := critterNames(changes[0].Arriving); names != "Koi" {
+		t.Errorf("expected the koi to arrive in May, got %q", names)
+	}
+	if names := critterNames(changes[0].Leaving); names != "Koi" {
+		t.Errorf("expected the koi to leave after May, got %q", names)
+	}
+	if names := critterNames(changes[1].Leaving); names != "Tarantula" {
+		t.Errorf("expected the tarantula to leave after April, got %q", names)
+	}
+	if names := critterNames(changes[6].Arriving); names != "Tarantula" {
+		t.Errorf("expected the tarantula to arrive in November, got %q", names)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	critters := ACNH{Fishes: []Fish{{Name: "Koi", Months: []int{4}, Location: "Pond", Price: 4000}}}
+	changes := monthChanges(critters, time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC), 2)
+	feed := atom(changes, Southern, "http://acnh.example")
+
+	b, err := xml.Marshal(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed atomFeed
+	if err := xml.Unmarshal(b, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Entries) != 2 || parsed.Updated != "2020-05-01T00:00:00Z" {
+		t.Fatalf("expected two entries updated in May, got %+v", parsed)
+	}
+	if parsed.Entries[0].Title != "May 2020: 1 arriving, 1 leaving" || !strings.Contains(parsed.Entries[0].Content.Body, "<li>Koi (Pond, 4000 bells)</li>") {
+		t.Errorf("unexpected entry %+v", parsed.Entries[0])
+	}
+	again := atom(changes, Southern, "http://acnh.example")
+	if again.Entries[0].ID != feed.Entries[0].ID || feed.Entries[0].ID == feed.Entries[1].ID {
+		t.Errorf("expected entry IDs to be stable and distinct")
+	}
+}
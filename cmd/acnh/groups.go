@@ -0,0 +1,411 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var (
+	errUnknownGroup = errors.New("no such group")
+	errBadInvite    = errors.New("that invite code doesn't match any group")
+)
+
+// Group is a bunch of islands that share a board.  Anybody with the invite
+// code can join one of their islands to it.
+type Group struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Invite  string    `json:"invite"`
+	Members []Profile `json:"members"`
+}
+
+// GroupSummary is a group as listed for one of its members.  Island is the
+// name of the member's island that's in it.
+type GroupSummary struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Invite  string `json:"invite"`
+	Members int    `json:"members"`
+	Island  string `json:"island"`
+}
+
+// GroupMember is one island in a group, and what's been donated on it.
+type GroupMember struct {
+	Name    string
+	Donated map[string][]string
+}
+
+// MemberProgress is how much of the critters one member has donated.
+type MemberProgress struct {
+	Name    string `json:"name"`
+	Donated int    `json:"donated"`
+	Total   int    `json:"total"`
+}
+
+func (p MemberProgress) Percent() int {
+	if p.Total == 0 {
+		return 0
+	}
+	return p.Donated * 100 / p.Total
+}
+
+// Handoff is a critter some members have donated and others haven't.  Those
+// who have know where and when to find it, so they can catch a spare for
+// those who haven't.
+type Handoff struct {
+	Category string   `json:"category"`
+	Name     string   `json:"name"`
+	Price    int      `json:"price"`
+	From     []string `json:"from"`
+	To       []string `json:"to"`
+}
+
+// GroupBoard is the whole group's critter progress put together.
+type GroupBoard struct {
+	Group    GroupSummary     `json:"group"`
+	Members  []MemberProgress `json:"members"`
+	Donated  int              `json:"donated"`
+	Total    int              `json:"total"`
+	Missing  []Critter        `json:"missing"`
+	Handoffs []Handoff        `json:"handoffs"`
+}
+
+func (b GroupBoard) Percent() int {
+	if b.Total == 0 {
+		return 0
+	}
+	return b.Donated * 100 / b.Total
+}
+
+// groupBoard merges the members' donated critters: what nobody has yet, who
+// can pass what on to whom, and how far along each member is.  Donated
+// counts what at least one member has.
+func groupBoard(critters ACNH, members []GroupMember) GroupBoard {
+	all := critters.critters()
+	board := GroupBoard{Total: len(all), Missing: []Critter{}, Handoffs: []Handoff{}}
+	done := make([]map[string]map[string]bool, len(members))
+	for i, m := range members {
+		done[i] = donatedSets(m.Donated)
+		board.Members = append(board.Members, MemberProgress{Name: m.Name, Total: len(all)})
+	}
+
+	for _, c := range all {
+		h := Handoff{Category: c.Category, Name: c.Name, Price: c.Price}
+		for i, m := range members {
+			if done[i][c.Category][c.ID] {
+				h.From = append(h.From, m.Name)
+				board.Members[i].Donated++
+			} else {
+				h.To = append(h.To, m.Name)
+			}
+		}
+		switch {
+		case len(h.From) == 0:
+			board.Missing = append(board.Missing, c)
+		case len(h.To) > 0:
+			board.Donated++
+			board.Handoffs = append(board.Handoffs, h)
+		default:
+			board.Donated++
+		}
+	}
+	sort.SliceStable(board.Members, func(i, j int) bool {
+		return board.Members[i].Donated > board.Members[j].Donated
+	})
+	// The ones most of the group still needs first.
+	sort.SliceStable(board.Handoffs, func(i, j int) bool {
+		return len(board.Handoffs[i].To) > len(board.Handoffs[j].To)
+	})
+	return board
+}
+
+// memberName is what an island is called on group boards.  The lock must be
+// held.
+func (s *Store) memberName(p Profile) string {
+	u := s.lookup(p.User)
+	i := s.lookupIsland(p)
+	if u.Username == "" {
+		return i.Name
+	}
+	return u.Username + " (" + i.Name + ")"
+}
+
+// summary describes the group to the user.  The lock must be held.
+func (s *Store) summary(g *Group, userID string) GroupSummary {
+	summary := GroupSummary{ID: g.ID, Name: g.Name, Invite: g.Invite, Members: len(g.Members)}
+	for _, m := range g.Members {
+		if m.User == userID {
+			summary.Island = s.lookupIsland(m).Name
+		}
+	}
+	return summary
+}
+
+// member returns the user's group with the given ID.  Groups the user isn't
+// in don't exist as far as they're concerned.  The lock must be held.
+func (s *Store) member(groupID, userID string) (*Group, error) {
+	g, ok := s.groups[groupID]
+	if !ok {
+		return nil, errUnknownGroup
+	}
+	for _, m := range g.Members {
+		if m.User == userID {
+			return g, nil
+		}
+	}
+	return nil, errUnknownGroup
+}
+
+// Groups returns the groups any of the user's islands are in, by name.
+func (s *Store) Groups(userID string) []GroupSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	groups := []GroupSummary{}
+	for id := range s.groups {
+		if g, err := s.member(id, userID); err == nil {
+			groups = append(groups, s.summary(g, userID))
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// CreateGroup starts a group with the profile's island in it.  The invite
+// code is as hard to guess as a session, since it's all it takes to see
+// everybody's islands.
+func (s *Store) CreateGroup(p Profile, name string) (GroupSummary, error) {
+	invite, _, err := newToken()
+	if err != nil {
+		return GroupSummary{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.Island = s.island(p).ID
+	g := &Group{ID: newIslandID(), Name: name, Invite: invite, Members: []Profile{p}}
+	if s.groups == nil {
+		s.groups = make(map[string]*Group)
+	}
+	s.groups[g.ID] = g
+	return s.summary(g, p.User), s.save()
+}
+
+// JoinGroup adds the profile's island to the group with the invite code.
+// Each user only gets one island in a group; joining again swaps it for
+// this one.
+func (s *Store) JoinGroup(p Profile, invite string) (GroupSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.Island = s.island(p).ID
+	for _, g := range s.groups {
+		if g.Invite == "" || g.Invite != invite {
+			continue
+		}
+		var members []Profile
+		for _, m := range g.Members {
+			if m.User != p.User {
+				members = append(members, m)
+			}
+		}
+		g.Members = append(members, p)
+		return s.summary(g, p.User), s.save()
+	}
+	return GroupSummary{}, errBadInvite
+}
+
+// LeaveGroup takes the user's island out of the group.  Groups go away once
+// everybody's left.
+func (s *Store) LeaveGroup(userID, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.member(groupID, userID); err != nil {
+		return err
+	}
+	s.leaveGroups(userID, groupID)
+	return s.save()
+}
+
+// leaveGroups takes the user out of the group, or out of all of them if
+// groupID is empty.  The lock must be held.
+func (s *Store) leaveGroups(userID, groupID string) {
+	for id, g := range s.groups {
+		if groupID != "" && id != groupID {
+			continue
+		}
+		var members []Profile
+		for _, m := range g.Members {
+			if m.User != userID {
+				members = append(members, m)
+			}
+		}
+		g.Members = members
+		if len(g.Members) == 0 {
+			delete(s.groups, id)
+		}
+	}
+}
+
+// dropIsland takes one of the user's islands out of every group it's in, for
+// when it's deleted.  The lock must be held.
+func (s *Store) dropIsland(p Profile) {
+	for id, g := range s.groups {
+		var members []Profile
+		for _, m := range g.Members {
+			if m != p {
+				members = append(members, m)
+			}
+		}
+		g.Members = members
+		if len(g.Members) == 0 {
+			delete(s.groups, id)
+		}
+	}
+}
+
+// GroupMembers returns the summary and members of one of the user's groups.
+func (s *Store) GroupMembers(groupID, userID string) (GroupSummary, []GroupMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, err := s.member(groupID, userID)
+	if err != nil {
+		return GroupSummary{}, nil, err
+	}
+	var members []GroupMember
+	for _, m := range g.Members {
+		if _, ok := s.users[m.User]; !ok {
+			continue
+		}
+		members = append(members, GroupMember{Name: s.memberName(m), Donated: s.lookupIsland(m).donatedLists()})
+	}
+	return s.summary(g, userID), members, nil
+}
+
+// board puts together the board for one of the user's groups.
+func board(critters ACNH, store *Store, groupID, userID string) (GroupBoard, error) {
+	summary, members, err := store.GroupMembers(groupID, userID)
+	if err != nil {
+		return GroupBoard{}, err
+	}
+	b := groupBoard(critters, members)
+	b.Group = summary
+	return b, nil
+}
+
+type GroupsPage struct {
+	Groups []GroupSummary
+	Board  *GroupBoard
+	Error  string
+}
+
+// groupsHandler lists the user's groups and lets them start, join and leave
+// them, posting back here.  With an "id" it shows that group's board.
+func groupsHandler(critters ACNH, languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		page := GroupsPage{}
+		status := http.StatusOK
+		if r.Method == http.MethodPost {
+			var err error
+			switch r.FormValue("action") {
+			case "create":
+				err = createGroup(store, id, r)
+			case "join":
+				_, err = store.JoinGroup(id, strings.TrimSpace(r.FormValue("invite")))
+			case "leave":
+				err = store.LeaveGroup(id.User, r.FormValue("id"))
+			default:
+				err = errors.New("unknown action")
+			}
+			if err == nil {
+				http.Redirect(w, r, "/groups", http.StatusSeeOther)
+				return
+			}
+			status = http.StatusBadRequest
+			page.Error = err.Error()
+		} else if groupID := r.FormValue("id"); groupID != "" {
+			b, err := board(critters, store, groupID, id.User)
+			if err != nil {
+				status = http.StatusNotFound
+				page.Error = err.Error()
+			} else {
+				page.Board = &b
+			}
+		}
+		page.Groups = store.Groups(id.User)
+		if err := render(w, r, languages, store, id, tmpl, status, page); err != nil {
+			logger.Log("failed rendering groups", "error", err)
+		}
+	}
+}
+
+func createGroup(store *Store, id Profile, r *http.Request) error {
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		return errors.New("groups need a name")
+	}
+	_, err := store.CreateGroup(id, name)
+	return err
+}
+
+// groupsAPIHandler lists the user's groups on GET, creates one from "name"
+// or joins one from "invite" on POST, and leaves the one in "id" on DELETE.
+func groupsAPIHandler(store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, store.Groups(id.User), logger)
+		case http.MethodPost:
+			var summary GroupSummary
+			var err error
+			if invite := strings.TrimSpace(r.FormValue("invite")); invite != "" {
+				summary, err = store.JoinGroup(id, invite)
+			} else if name := strings.TrimSpace(r.FormValue("name")); name != "" {
+				summary, err = store.CreateGroup(id, name)
+			} else {
+				http.Error(w, "either name or invite is required", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				if errors.Is(err, errBadInvite) {
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				logger.Log("failed saving group", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, summary, logger)
+		case http.MethodDelete:
+			if err := store.LeaveGroup(id.User, r.FormValue("id")); err != nil {
+				if errors.Is(err, errUnknownGroup) {
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				logger.Log("failed leaving group", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// groupBoardHandler returns the board for the group in "id".
+func groupBoardHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := board(critters, store, r.FormValue("id"), store.userID(w, r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, b, logger)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroupBoard(t *testing.T) {
+	critters := ACNH{
+		Bugs:   []Bug{{ID: "ant", Name: "Ant", Price: 80}, {ID: "bee", Name: "Bee", Price: 200}},
+		Fishes: []Fish{{ID: "koi", Name: "Koi", Price: 4000}},
+	}
+	members := []GroupMember{
+		{Name: "Daisy", Donated: map[string][]string{"bugs": {"ant"}}},
+		{Name: "Mae", Donated: map[string][]string{"bugs": {"ant", "bee"}}},
+	}
+
+	board := groupBoard(critters, members)
+	if board.Donated != 2 || board.Total != 3 {
+		t.Errorf("expected 2 of 3 between them, got %d of %d", board.Donated, board.Total)
+	}
+	if len(board.Missing) != 1 || board.Missing[0].Name != "Koi" {
+		t.Errorf("expected only the koi to be missing, got %+v", board.Missing)
+	}
+	want := []Handoff{{Category: "bugs", Name: "Bee", Price: 200, From: []string{"Mae"}, To: []string{"Daisy"}}}
+	if !reflect.DeepEqual(board.Handoffs, want) {
+		t.Errorf("expected %+v, got %+v", want, board.Handoffs)
+	}
+	if board.Members[0].Name != "Mae" || board.Members[0].Donated != 2 || board.Members[1].Donated != 1 {
+		t.Errorf("expected members ordered by progress, got %+v", board.Members)
+	}
+}
+
+func TestJoinAndLeaveGroups(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	daisy, mae := Profile{User: "daisy"}, Profile{User: "mae"}
+	if err := store.SetDonated(mae, "bugs", "bee", true); err != nil {
+		t.Fatal(err)
+	}
+
+	group, err := store.CreateGroup(daisy, "Neighbours")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.JoinGroup(mae, "wrong"); err != errBadInvite {
+		t.Errorf("expected a wrong invite to fail, got %v", err)
+	}
+	if len(group.Invite) != 64 {
+		t.Errorf("expected the invite to be a full token, got %q", group.Invite)
+	}
+	if _, err := store.JoinGroup(mae, group.Invite); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.GroupMembers(group.ID, "stranger"); err != errUnknownGroup {
+		t.Errorf("expected outsiders not to see the group, got %v", err)
+	}
+	_, members, err := store.GroupMembers(group.ID, "daisy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[1].Donated["bugs"][0] != "bee" {
+		t.Errorf("expected both members with their donations, got %+v", members)
+	}
+
+	reloaded, err := loadStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups := reloaded.Groups("mae"); len(groups) != 1 || groups[0].Members != 2 {
+		t.Errorf("expected the group to be saved, got %+v", groups)
+	}
+
+	if err := store.LeaveGroup("daisy", group.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.LeaveGroup("mae", group.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.groups[group.ID]; ok {
+		t.Errorf("expected the group to go away once everybody left")
+	}
+}
+
+func TestDeleteIslandLeavesGroups(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	daisy := Profile{User: "daisy"}
+	group, err := store.CreateGroup(daisy, "Neighbours")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddIsland("daisy", IslandSettings{Name: "Second", Timezone: defaultTimezone}); err != nil {
+		t.Fatal(err)
+	}
+	first := store.Islands(daisy)[0]
+	if first.Name == "Second" {
+		first = store.Islands(daisy)[1]
+	}
+	if err := store.DeleteIsland(Profile{User: "daisy", Island: first.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.groups[group.ID]; ok {
+		t.Errorf("expected the deleted island's group to go away with it, got %+v", store.groups[group.ID])
+	}
+}
+
+func TestGroupsHandlerEscapes(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	tmpl, err := loadTemplate("groups", nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := store.CreateGroup(Profile{User: "daisy"}, "<script>alert(1)</script>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{"/groups", "/groups?id=" + group.ID} {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.AddCookie(&http.Cookie{Name: userCookie, Value: "daisy"})
+		w := httptest.NewRecorder()
+		groupsHandler(slashCritters(), Languages{"en": english}, store, tmpl, nopLogger{})(w, r)
+		if body := w.Body.String(); strings.Contains(body, "<script>alert") || !strings.Contains(body, "&lt;script&gt;alert") {
+			t.Errorf("expected the group name to be escaped on %s, got %s", target, body)
+		}
+	}
+}
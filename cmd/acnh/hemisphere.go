@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+// Hemisphere decides which months critters show up in.
+type Hemisphere = season.Hemisphere
+
+const (
+	Northern = season.Northern
+	Southern = season.Southern
+)
+
+// forHemisphere returns a copy of the critters, recipes and materials with
+// their months moved to h.  Everything else is shared with a.
+func (a ACNH) forHemisphere(h Hemisphere) ACNH {
+	if h != Southern {
+		return a
+	}
+	shifted := a
+	shifted.Bugs = make([]Bug, len(a.Bugs))
+	for i, b := range a.Bugs {
+		b.Months = season.Shift(b.Months, h)
+		shifted.Bugs[i] = b
+	}
+	shifted.Fishes = make([]Fish, len(a.Fishes))
+	for i, f := range a.Fishes {
+		f.Months = season.Shift(f.Months, h)
+		shifted.Fishes[i] = f
+	}
+	shifted.SeaCreatures = make([]SeaCreature, len(a.SeaCreatures))
+	for i, s := range a.SeaCreatures {
+		s.Months = season.Shift(s.Months, h)
+		shifted.SeaCreatures[i] = s
+	}
+	shifted.Recipes = make([]Recipe, len(a.Recipes))
+	for i, r := range a.Recipes {
+		r.Months = season.Shift(r.Months, h)
+		shifted.Recipes[i] = r
+	}
+	shifted.Materials = make([]Material, len(a.Materials))
+	for i, m := range a.Materials {
+		m.Months = season.Shift(m.Months, h)
+		shifted.Materials[i] = m
+	}
+	return shifted
+}
+
+// Hemisphere returns the island's hemisphere, northern unless they've said
+// otherwise.
+func (s *Store) Hemisphere(p Profile) Hemisphere {
+	h := Northern
+	s.view(p, func(i *Island) {
+		if i.Hemisphere != "" {
+			h = i.Hemisphere
+		}
+	})
+	return h
+}
+
+// hemisphereFor is the hemisphere asked for in the request, or the island's
+// own if it didn't ask.
+func hemisphereFor(r *http.Request, store *Store, id Profile) Hemisphere {
+	if h, err := season.ParseHemisphere(r.URL.Query().Get("hemisphere")); err == nil {
+		return h
+	}
+	return store.Hemisphere(id)
+}
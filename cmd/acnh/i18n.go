@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Language is one language the site can be shown in.  Messages maps the
+// English text of the site to this language's, and anything missing is
+// left in English.  Messages with %s in them take arguments, the same as
+// fmt.Sprintf.
+type Language struct {
+	Code     string            `json:"code"`
+	Name     string            `json:"name"`
+	Messages map[string]string `json:"-"`
+}
+
+// english is what the site is written in, so it doesn't need any messages.
+var english = Language{Code: "en", Name: "English"}
+
+// Languages are the languages there are messages for, by code.
+type Languages map[string]Language
+
+// loadLanguages reads every language's messages from dir, one file per
+// language named after its code, like "es.json".  English is always there.
+func loadLanguages(dir string) (Languages, error) {
+	languages := Languages{english.Code: english}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var l struct {
+			Name     string            `json:"name"`
+			Messages map[string]string `json:"messages"`
+		}
+		if err := json.Unmarshal(b, &l); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", file, err)
+		}
+		code := strings.TrimSuffix(filepath.Base(file), ".json")
+		languages[code] = Language{Code: code, Name: l.Name, Messages: l.Messages}
+	}
+	return languages, nil
+}
+
+// sorted is every language, English first and the rest by code.
+func (ls Languages) sorted() []Language {
+	var sorted []Language
+	for _, l := range ls {
+		sorted = append(sorted, l)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Code == english.Code || sorted[j].Code == english.Code {
+			return sorted[i].Code == english.Code
+		}
+		return sorted[i].Code < sorted[j].Code
+	})
+	return sorted
+}
+
+// acceptLanguages is the language codes in an Accept-Language header, most
+// wanted first.  Regional variants are followed by the language they're a
+// variant of, so "es-MX" will settle for "es".
+func acceptLanguages(header string) []string {
+	type weighted struct {
+		code string
+		q    float64
+	}
+	var accepted []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		code := strings.ToLower(strings.TrimSpace(fields[0]))
+		if code == "" || code == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		accepted = append(accepted, weighted{code, q})
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].q > accepted[j].q
+	})
+	var codes []string
+	for _, a := range accepted {
+		codes = append(codes, a.code)
+		if i := strings.Index(a.code, "-"); i > 0 {
+			codes = append(codes, a.code[:i])
+		}
+	}
+	return codes
+}
+
+// Language is the code of the language the user picked, or "" if they
+// haven't.
+func (s *Store) Language(p Profile) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lookup(p.User).Language
+}
+
+func (s *Store) SetLanguage(p Profile, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user(p.User).Language = code
+	return s.save()
+}
+
+// languageFor is the language asked for in the request, or else the one the
+// user picked, or else the first one their browser asks for that there are
+// messages for.  Failing all that, it's English.
+func languageFor(r *http.Request, languages Languages, store *Store, id Profile) Language {
+	for _, code := range append([]string{r.URL.Query().Get("lang"), store.Language(id)}, acceptLanguages(r.Header.Get("Accept-Language"))...) {
+		if l, ok := languages[code]; ok {
+			return l
+		}
+	}
+	return english
+}
+
+// T translates msg, filling in args if there are any.
+func (l Language) T(msg string, args ...interface{}) string {
+	if translated, ok := l.Messages[msg]; ok && translated != "" {
+		msg = translated
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// CritterName is a critter's name in this language, from its names in
+// other languages and its English one.
+func (l Language) CritterName(names map[string]string, name string) string {
+	if n, ok := names[l.Code]; ok {
+		return n
+	}
+	return name
+}
+
+// Month is the name of month, 0 being January.
+func (l Language) Month(month int) string {
+	return l.T(time.Month(month + 1).String())
+}
+
+// Months is what displayMonths says, in this language.
+func (l Language) Months(months []int) string {
+	if len(months) >= 12 {
+		return l.T("All year")
+	}
+	sorted := append([]int(nil), months...)
+	sort.Ints(sorted)
+	names := make([]string, len(sorted))
+	for i, m := range sorted {
+		names[i] = l.T(time.Month(m + 1).String()[:3])
+	}
+	return strings.Join(names, ", ")
+}
+
+// Timing is what Timing.Display says, in this language.
+func (l Language) Timing(t Timing) string {
+	switch {
+	case t.Status == StatusOutOfSeason && t.NextMonth != nil:
+		return l.T("Out of season until %s", l.Month(*t.NextMonth))
+	case t.AvailableAllDay:
+		return l.T("All Day")
+	case t.AvailableNow:
+		return l.T("Until %s", t.DisplayUntil())
+	}
+	return l.T("Starting at %s", t.DisplayAt())
+}
+
+// funcs are the template functions for showing things in this language.
+func (l Language) funcs() template.FuncMap {
+	return template.FuncMap{
+		"lang":   func() string { return l.Code },
+		"t":      l.T,
+		"name":   l.CritterName,
+		"month":  l.Month,
+		"months": l.Months,
+		"timing": l.Timing,
+	}
+}
+
+// execute runs tmpl in this language.  Every template is parsed with
+// English's funcs, so they get swapped out on a copy.
+func (l Language) execute(tmpl *template.Template, w io.Writer, data interface{}) error {
+	t, err := tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	return t.Funcs(l.funcs()).Execute(w, data)
+}
+
+// render writes the status and runs tmpl in the language for the request,
+// saying which one that was.
+func render(w http.ResponseWriter, r *http.Request, languages Languages, store *Store, id Profile, tmpl *template.Template, status int, data interface{}) error {
+	language := languageFor(r, languages, store, id)
+	w.Header().Set("Content-Language", language.Code)
+	w.Header().Add("Vary", "Accept-Language")
+	w.WriteHeader(status)
+	return language.execute(tmpl, w, data)
+}
+
+// languageHandler returns the user's chosen language and the ones they can
+// choose from on GET, and sets it on POST.  An empty language goes back to
+// whatever the browser asks for.
+func languageHandler(languages Languages, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, struct {
+				Language  string     `json:"language"`
+				Languages []Language `json:"languages"`
+			}{store.Language(id), languages.sorted()}, logger)
+		case http.MethodPost:
+			code := r.FormValue("language")
+			if _, ok := languages[code]; !ok && code != "" {
+				http.Error(w, "unknown language", http.StatusBadRequest)
+				return
+			}
+			if err := store.SetLanguage(id, code); err != nil {
+				logger.Log("failed saving language", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+func TestAcceptLanguages(t *testing.T) {
+	tests := []struct {
+		Header string
+		Want   []string
+	}{
+		{"", nil},
+		{"es", []string{"es"}},
+		{"en;q=0.8, es-MX, *;q=0.1", []string{"es-mx", "es", "en"}},
+		{"de;q=0, fr;q=0.5, es;q=0.7", []string{"es", "fr"}},
+	}
+	for _, test := range tests {
+		if got := acceptLanguages(test.Header); !reflect.DeepEqual(got, test.Want) {
+			t.Errorf("acceptLanguages(%q) = %v, want %v", test.Header, got, test.Want)
+		}
+	}
+}
+
+func TestLanguageFor(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	languages := Languages{"en": english, "es": {Code: "es"}, "de": {Code: "de"}}
+	p := Profile{User: "someone"}
+	pick := func(target, accept string) string {
+		r := httptest.NewRequest("GET", target, nil)
+		r.Header.Set("Accept-Language", accept)
+		return languageFor(r, languages, store, p).Code
+	}
+
+	if got := pick("/", "fr, es-ES;q=0.9"); got != "es" {
+		t.Errorf("expected the browser's Spanish, got %s", got)
+	}
+	if got := pick("/", "fr"); got != "en" {
+		t.Errorf("expected English when there's nothing better, got %s", got)
+	}
+	if err := store.SetLanguage(p, "de"); err != nil {
+		t.Fatal(err)
+	}
+	if got := pick("/", "es"); got != "de" {
+		t.Errorf("expected the user's setting over the browser, got %s", got)
+	}
+	if got := pick("/?lang=es", "de"); got != "es" {
+		t.Errorf("expected the request to win, got %s", got)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	es := Language{Code: "es", Messages: map[string]string{
+		"Until %s":               "Hasta las %s",
+		"Out of season until %s": "Fuera de temporada hasta %s",
+		"November":               "noviembre",
+	}}
+	critters := slashCritters().critters()
+	night := time.Date(2020, time.January, 5, 21, 30, 0, 0, time.UTC)
+
+	if got := es.Timing(season.At(critters[0].Months, critters[0].HourMap, night)); got != "Hasta las 4AM" {
+		t.Errorf("expected the tarantula's timing in Spanish, got %q", got)
+	}
+	if got := es.Timing(season.At(critters[0].Months, critters[0].HourMap, night.AddDate(0, 5, 0))); got != "Fuera de temporada hasta noviembre" {
+		t.Errorf("expected the month in Spanish too, got %q", got)
+	}
+	if got := es.Timing(season.At(critters[1].Months, critters[1].HourMap, night)); got != "All Day" {
+		t.Errorf("expected anything untranslated in English, got %q", got)
+	}
+	if got := es.CritterName(map[string]string{"es": "Tarántula"}, "Tarantula"); got != "Tarántula" {
+		t.Errorf("expected the Spanish name, got %q", got)
+	}
+	if got := es.CritterName(nil, "Coelacanth"); got != "Coelacanth" {
+		t.Errorf("expected the English name without a Spanish one, got %q", got)
+	}
+
+	tmpl, err := template.New("test").Funcs(english.funcs()).Parse(`{{ lang }}: {{ t "Until %s" "4AM" }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := es.execute(tmpl, &b, nil); err != nil || b.String() != "es: Hasta las 4AM" {
+		t.Errorf("expected the template in Spanish, got %q: %v", b.String(), err)
+	}
+}
+
+// Every language should have every message the main page asks for, so
+// nobody gets a page that's half in English.
+func TestLoadLanguages(t *testing.T) {
+	languages, err := loadLanguages("i18n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(languages) < 2 || languages.sorted()[0].Code != "en" {
+		t.Fatalf("expected English first and then some, got %v", languages.sorted())
+	}
+	pages, err := filepath.Glob("templates/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, p := range pages {
+		page, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range regexp.MustCompile(`{{ t "([^"]*)"`).FindAllStringSubmatch(string(page), -1) {
+			keys = append(keys, m[1])
+		}
+	}
+	for _, l := range languages {
+		if l.Code == english.Code {
+			continue
+		}
+		if l.Name == "" {
+			t.Errorf("expected %s to have a name", l.Code)
+		}
+		for _, k := range keys {
+			if l.Messages[k] == "" {
+				t.Errorf("expected %s to translate %q", l.Code, k)
+			}
+			if strings.Count(l.Messages[k], "%s") != strings.Count(k, "%s") {
+				t.Errorf("expected %s's %q to take the same arguments", l.Code, k)
+			}
+		}
+	}
+}
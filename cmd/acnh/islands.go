@@ -0,0 +1,331 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+const (
+	defaultIslandName = "My island"
+	defaultTimezone   = "America/Los_Angeles"
+	// firstIslandID is the ID of everybody's first island.  It's the same
+	// before the user's been saved as after, so pages shown to somebody new
+	// still point at it once they've changed something.
+	firstIslandID = "home"
+)
+
+var (
+	errLastIsland    = errors.New("can't delete your only island")
+	errUnknownIsland = errors.New("no such island")
+)
+
+// Profile picks out one of a user's islands.  An empty or unknown Island
+// means whichever one they're currently looking at.
+type Profile struct {
+	User   string `json:"user"`
+	Island string `json:"island"`
+}
+
+// profile returns the profile for the request: the user's own, on the
+// island in the "profile" parameter if there is one.
+func (s *Store) profile(w http.ResponseWriter, r *http.Request) Profile {
+	return Profile{User: s.userID(w, r), Island: r.FormValue("profile")}
+}
+
+// IslandSettings is an island without its progress, for listing and
+// editing.
+type IslandSettings struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Hemisphere Hemisphere `json:"hemisphere"`
+	Timezone   string     `json:"timezone"`
+	Current    bool       `json:"current"`
+}
+
+func (i *Island) settings() IslandSettings {
+	return IslandSettings{ID: i.ID, Name: i.Name, Hemisphere: i.Hemisphere, Timezone: i.Timezone}
+}
+
+// addIsland adds a new, empty island and makes it the current one.
+func (u *User) addIsland(id, name string, h Hemisphere, timezone string) *Island {
+	if u.Islands == nil {
+		u.Islands = make(map[string]*Island)
+	}
+	if h == "" {
+		h = Northern
+	}
+	i := &Island{
+		ID:         id,
+		Name:       name,
+		Hemisphere: h,
+		Timezone:   timezone,
+		Donated:    make(map[string]map[string]bool),
+	}
+	u.Islands[i.ID] = i
+	u.Current = i.ID
+	return i
+}
+
+// sortedIslands returns the user's islands by name.
+func (u *User) sortedIslands() []*Island {
+	islands := make([]*Island, 0, len(u.Islands))
+	for _, i := range u.Islands {
+		islands = append(islands, i)
+	}
+	sort.Slice(islands, func(a, b int) bool {
+		if islands[a].Name != islands[b].Name {
+			return islands[a].Name < islands[b].Name
+		}
+		return islands[a].ID < islands[b].ID
+	})
+	return islands
+}
+
+func newIslandID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "island"
+	}
+	return hex.EncodeToString(b)
+}
+
+// Island returns the settings of the profile's island.
+func (s *Store) Island(p Profile) IslandSettings {
+	var settings IslandSettings
+	s.view(p, func(i *Island) {
+		settings = i.settings()
+	})
+	settings.Current = true
+	return settings
+}
+
+// Islands returns all of the user's islands, with the one the profile picks
+// out marked current.
+func (s *Store) Islands(p Profile) []IslandSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := s.lookupIsland(p).ID
+	var islands []IslandSettings
+	for _, i := range s.lookup(p.User).sortedIslands() {
+		settings := i.settings()
+		settings.Current = i.ID == current
+		islands = append(islands, settings)
+	}
+	return islands
+}
+
+// AddIsland adds an island for the user and switches them to it.
+func (s *Store) AddIsland(userID string, settings IslandSettings) (IslandSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.user(userID).addIsland(newIslandID(), settings.Name, settings.Hemisphere, settings.Timezone)
+	settings = i.settings()
+	settings.Current = true
+	return settings, s.save()
+}
+
+// UpdateIsland changes the name, hemisphere and time zone of one of the
+// user's islands.
+func (s *Store) UpdateIsland(p Profile, settings IslandSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lookup(p.User).Islands[p.Island]; !ok {
+		return errUnknownIsland
+	}
+	i := s.user(p.User).Islands[p.Island]
+	i.Name = settings.Name
+	i.Hemisphere = settings.Hemisphere
+	i.Timezone = settings.Timezone
+	return s.save()
+}
+
+// SelectIsland makes one of the user's islands the one they see by default.
+func (s *Store) SelectIsland(p Profile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lookup(p.User).Islands[p.Island]; !ok {
+		return errUnknownIsland
+	}
+	u := s.user(p.User)
+	u.Current = p.Island
+	return s.save()
+}
+
+// DeleteIsland deletes one of the user's islands, as long as it isn't their
+// last one, and takes it out of any groups it was in.
+func (s *Store) DeleteIsland(p Profile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.lookup(p.User)
+	if _, ok := u.Islands[p.Island]; !ok {
+		return errUnknownIsland
+	}
+	if len(u.Islands) <= 1 {
+		return errLastIsland
+	}
+	delete(u.Islands, p.Island)
+	s.dropIsland(p)
+	if u.Current == p.Island {
+		u.Current = u.sortedIslands()[0].ID
+	}
+	return s.save()
+}
+
+// Now returns the current time on the profile's island.
+func (s *Store) Now(p Profile) (time.Time, error) {
+	return now(s.Island(p).Timezone)
+}
+
+// parseIslandSettings reads an island's settings out of a form.
+func parseIslandSettings(r *http.Request) (IslandSettings, error) {
+	settings := IslandSettings{
+		Name:     strings.TrimSpace(r.FormValue("name")),
+		Timezone: strings.TrimSpace(r.FormValue("timezone")),
+	}
+	if settings.Name == "" {
+		return settings, errors.New("islands need a name")
+	}
+	if settings.Timezone == "" {
+		settings.Timezone = defaultTimezone
+	}
+	if _, err := time.LoadLocation(settings.Timezone); err != nil {
+		return settings, errors.New("unknown time zone: " + settings.Timezone)
+	}
+	h, err := season.ParseHemisphere(r.FormValue("hemisphere"))
+	if err != nil {
+		return settings, err
+	}
+	settings.Hemisphere = h
+	return settings, nil
+}
+
+// saveIsland creates or updates an island from a form, depending on whether
+// it has an "id".
+func saveIsland(store *Store, userID string, r *http.Request) (IslandSettings, error) {
+	settings, err := parseIslandSettings(r)
+	if err != nil {
+		return settings, err
+	}
+	if id := r.FormValue("id"); id != "" {
+		p := Profile{User: userID, Island: id}
+		if err := store.UpdateIsland(p, settings); err != nil {
+			return settings, err
+		}
+		return store.Island(p), nil
+	}
+	return store.AddIsland(userID, settings)
+}
+
+type IslandsPage struct {
+	Islands     []IslandSettings
+	Hemispheres []Hemisphere
+	// CalendarToken is only set right after it's made, since that's the
+	// only time it's known.
+	CalendarToken string
+	HasCalendar   bool
+	Error         string
+}
+
+// islandsHandler lists the user's islands and lets them add, edit, switch
+// between and delete them, and make calendar links.  Everything posts back
+// here and redirects, except making calendar links, which shows them.
+func islandsHandler(languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.userID(w, r)
+		page := IslandsPage{Hemispheres: season.Hemispheres}
+		status := http.StatusOK
+		if r.Method == http.MethodPost {
+			p := Profile{User: id, Island: r.FormValue("id")}
+			var err error
+			switch r.FormValue("action") {
+			case "select":
+				err = store.SelectIsland(p)
+			case "delete":
+				err = store.DeleteIsland(p)
+			case "calendar":
+				page.CalendarToken, err = store.NewCalendarToken(id)
+			default:
+				_, err = saveIsland(store, id, r)
+			}
+			switch {
+			case err != nil:
+				status = http.StatusBadRequest
+				page.Error = err.Error()
+			case page.CalendarToken == "":
+				http.Redirect(w, r, "/islands", http.StatusSeeOther)
+				return
+			}
+		}
+		page.Islands = store.Islands(Profile{User: id})
+		page.HasCalendar = store.HasCalendarToken(id)
+		if err := render(w, r, languages, store, Profile{User: id}, tmpl, status, page); err != nil {
+			logger.Log("failed rendering islands", "error", err)
+		}
+	}
+}
+
+// islandsAPIHandler lists the user's islands on GET, creates or updates one
+// on POST, and deletes the one in "id" on DELETE.
+func islandsAPIHandler(store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.userID(w, r)
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, store.Islands(Profile{User: id, Island: r.FormValue("profile")}), logger)
+		case http.MethodPost:
+			settings, err := saveIsland(store, id, r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, settings, logger)
+		case http.MethodDelete:
+			if err := store.DeleteIsland(Profile{User: id, Island: r.FormValue("id")}); err != nil {
+				if errors.Is(err, errUnknownIsland) {
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				if errors.Is(err, errLastIsland) {
+					http.Error(w, err.Error(), http.StatusConflict)
+					return
+				}
+				logger.Log("failed deleting island", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// currentIslandHandler switches the user to the island in "id".
+func currentIslandHandler(store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if err := store.SelectIsland(Profile{User: store.userID(w, r), Island: r.FormValue("id")}); err != nil {
+			if errors.Is(err, errUnknownIsland) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			logger.Log("failed switching islands", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreMigratesToIslands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acnh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "store.json")
+	old := `{"someone": {"donated": {"bugs": {"Ant": true}}, "learned": {"ladder": true}}}`
+	if err := ioutil.WriteFile(path, []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := loadStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Profile{User: "someone"}
+	islands := store.Islands(p)
+	if len(islands) != 1 || !islands[0].Current || islands[0].Hemisphere != Northern {
+		t.Fatalf("expected one northern island, got %+v", islands)
+	}
+	if !store.Donated(p, "bugs")["Ant"] || !store.Learned(p)["ladder"] {
+		t.Errorf("expected old progress to move onto the island")
+	}
+
+	second, err := store.AddIsland("someone", IslandSettings{Name: "Second", Hemisphere: Northern, Timezone: "Asia/Tokyo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Island(p).ID != second.ID {
+		t.Errorf("expected a new island to become current")
+	}
+	if len(store.Donated(p, "bugs")) != 0 {
+		t.Errorf("expected the new island to start empty")
+	}
+	first := Profile{User: "someone", Island: islands[0].ID}
+	if !store.Donated(first, "bugs")["Ant"] {
+		t.Errorf("expected the first island to be reachable by its profile")
+	}
+	if err := store.DeleteIsland(Profile{User: "someone", Island: "nope"}); err != errUnknownIsland {
+		t.Errorf("expected deleting an unknown island to fail, got %v", err)
+	}
+	if err := store.DeleteIsland(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteIsland(Profile{User: "someone", Island: second.ID}); err != errLastIsland {
+		t.Errorf("expected deleting the last island to fail, got %v", err)
+	}
+
+	reloaded, err := loadStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Island(p); got.ID != second.ID || got.Timezone != "Asia/Tokyo" {
+		t.Errorf("expected the second island to survive a reload, got %+v", got)
+	}
+}
+
+func TestLookingDoesNotAddUsers(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	p := Profile{User: "passing by"}
+	store.Islands(p)
+	store.Island(p)
+	store.AllDonated(p)
+	store.Donated(p, "bugs")
+	store.Learned(p)
+	store.Residents(p)
+	store.Language(p)
+	if err := store.SetDonated(Profile{User: "someone"}, "bugs", "ant", true); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := loadStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.users["passing by"]; ok || len(reloaded.users) != 1 {
+		t.Errorf("expected only the user who donated to be saved, got %d users", len(reloaded.users))
+	}
+
+	// What a new user is shown still works once they change it.
+	islands := store.Islands(p)
+	if err := store.UpdateIsland(Profile{User: p.User, Island: islands[0].ID}, IslandSettings{Name: "Home", Hemisphere: Southern, Timezone: "UTC"}); err != nil {
+		t.Fatal(err)
+	}
+	if island := store.Island(p); island.Name != "Home" || island.Hemisphere != Southern {
+		t.Errorf("expected the new user's island to be saved, got %+v", island)
+	}
+}
@@ -1,7 +1,7 @@
 let acnh = function() {
     let checkboxes = document.querySelectorAll(".donated_checkbox");
     let critters = {};
-    ["bugs", "fishes", "sea_creatures", "umbrellas"].forEach(function(listName) {
+    ["bugs", "fishes", "sea_creatures", "umbrellas", "fossils", "art"].forEach(function(listName) {
         critters[listName] = [];
         let str = window.localStorage.getItem(listName);
         if (str != null && str != "") {
@@ -12,20 +12,36 @@ let acnh = function() {
     checkboxes.forEach(function(checkbox) {
         checkbox.addEventListener("click", function(self) {
             let critterType = checkbox.dataset["critter_type"];
+            let key = donationKey(self.currentTarget);
             if (self.currentTarget.checked) {
-                if (!critters[critterType].includes(self.currentTarget.dataset["name"])) {
-                    critters[critterType].push(self.currentTarget.dataset["name"]);
+                if (!critters[critterType].includes(key)) {
+                    critters[critterType].push(key);
                 }
             } else {
-                if (critters[critterType].includes(self.currentTarget.dataset["name"])) {
-                    critters[critterType] = removeItemAll(critters[critterType], self.currentTarget.dataset["name"]);
+                if (critters[critterType].includes(key)) {
+                    critters[critterType] = removeItemAll(critters[critterType], key);
                 }
             }
             let str = critters[critterType].join(",");
             window.localStorage.setItem(self.currentTarget.dataset["critter_type"], str);
+            saveDonated(critterType, key, self.currentTarget.checked);
         })
     });
 
+    document.getElementById("island").addEventListener("change", function(e) {
+        let body = new URLSearchParams();
+        body.append("id", e.currentTarget.value);
+        fetch("/api/islands/current", {method: "POST", body: body}).then(function() {
+            window.location.reload();
+        });
+    });
+    document.getElementById("language").addEventListener("change", function(e) {
+        let body = new URLSearchParams();
+        body.append("language", e.currentTarget.value);
+        fetch("/api/language", {method: "POST", body: body}).then(function() {
+            window.location.reload();
+        });
+    });
     document.getElementById("show_donated_bugs").addEventListener("click", function(e) {
         setDonatedBugsVisibility(e.currentTarget.checked);
     });
@@ -35,12 +51,45 @@ let acnh = function() {
     document.getElementById("show_donated_sea_creatures").addEventListener("click", function(e) {
         setDonatedSCVisibility(e.currentTarget.checked);
     });
-    document.getElementById("show_aquired_umbrellas").addEventListener("click", function(e) {
-        setDonatedUmbrellaVisibility(e.currentTarget.checked);
+    document.getElementById("show_donated_fossils").addEventListener("click", function(e) {
+        setDonatedTableVisibility("fossil_table", e.currentTarget.checked);
+    });
+    document.getElementById("show_donated_art").addEventListener("click", function(e) {
+        setDonatedTableVisibility("art_table", e.currentTarget.checked);
+    });
+
+    // The server is the source of truth now, but the first time we talk to it
+    // anything only ticked off in this browser gets sent up so nobody loses
+    // their old progress.  That was from before there were IDs, so it goes up
+    // by name and the server works out what it was.  Either way, what's kept
+    // here afterwards is what the server has, by ID.
+    fetch("/api/donations").then(function(response) {
+        return response.json();
+    }).then(function(server) {
+        let synced = window.localStorage.getItem("synced") === "true";
+        Object.keys(critters).forEach(function(listName) {
+            let fromServer = server[listName] || [];
+            if (!synced) {
+                critters[listName].forEach(function(name) {
+                    if (!fromServer.includes(name)) {
+                        saveDonated(listName, name, true, "name");
+                        fromServer.push(name);
+                    }
+                });
+            }
+            critters[listName] = fromServer;
+            window.localStorage.setItem(listName, fromServer.join(","));
+        });
+        window.localStorage.setItem("synced", "true");
+        markDonatedRows(critters);
+    }).catch(function() {
+        markDonatedRows(critters);
     });
+};
 
+function markDonatedRows(critters) {
     document.querySelectorAll(".bug_row").forEach(function(row) {
-        if (!critters["bugs"].includes(row.dataset["name"])) {
+        if (!critters["bugs"].includes(donationKey(row))) {
             row.classList.remove("hidden");
         } else {
             row.classList.add("donated");
@@ -48,7 +97,7 @@ let acnh = function() {
         }
     })
     document.querySelectorAll(".fish_row").forEach(function(row) {
-        if (!critters["fishes"].includes(row.dataset["name"])) {
+        if (!critters["fishes"].includes(donationKey(row))) {
             row.classList.remove("hidden");
         } else {
             row.classList.add("donated");
@@ -56,22 +105,46 @@ let acnh = function() {
         }
     })
     document.querySelectorAll(".sea_creature_row").forEach(function(row) {
-        if (!critters["sea_creatures"].includes(row.dataset["name"])) {
+        if (!critters["sea_creatures"].includes(donationKey(row))) {
             row.classList.remove("hidden");
         } else {
             row.classList.add("donated");
             row.children[0].querySelector('input').checked = true;
         }
     })
-    document.querySelectorAll(".umbrella_row").forEach(function(row) {
-        if (!critters["umbrellas"].includes(row.dataset["name"])) {
+    document.querySelectorAll(".fossil_row").forEach(function(row) {
+        if (!critters["fossils"].includes(donationKey(row))) {
             row.classList.remove("hidden");
         } else {
             row.classList.add("donated");
             row.children[0].querySelector('input').checked = true;
         }
     })
-};
+    document.querySelectorAll(".art_row").forEach(function(row) {
+        if (!critters["art"].includes(donationKey(row))) {
+            row.classList.remove("hidden");
+        } else {
+            row.classList.add("donated");
+            row.children[0].querySelector('input').checked = true;
+        }
+    })
+}
+
+// donationKey is what a row or checkbox is donated under: its ID, or its
+// name on the odd row that doesn't have one.
+function donationKey(elem) {
+    return elem.dataset["id"] || elem.dataset["name"];
+}
+
+// saveDonated sends a donation to the server, by ID unless field says
+// otherwise.
+function saveDonated(critterType, key, donated, field) {
+    let body = new URLSearchParams();
+    body.append("category", critterType);
+    body.append(field || "id", key);
+    body.append("donated", donated);
+    fetch("/api/donations", {method: "POST", body: body});
+}
 
 function setDonatedBugsVisibility(visible) {
     document.querySelectorAll("#bug_table .donated").forEach(function(elem) {
@@ -103,8 +176,8 @@ function setDonatedSCVisibility(visible) {
     });
 }
 
-function setDonatedUmbrellaVisibility(visible) {
-    document.querySelectorAll("#umbrella_table .donated").forEach(function(elem) {
+function setDonatedTableVisibility(tableID, visible) {
+    document.querySelectorAll("#" + tableID + " .donated").forEach(function(elem) {
         if (visible) {
             elem.classList.remove("hidden");
         } else {
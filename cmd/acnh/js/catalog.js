@@ -0,0 +1,19 @@
+let catalog = function() {
+    document.querySelectorAll(".acquired_checkbox").forEach(function(checkbox) {
+        checkbox.addEventListener("click", function(self) {
+            let row = self.currentTarget.closest("tr");
+            if (self.currentTarget.checked) {
+                row.classList.add("donated");
+            } else {
+                row.classList.remove("donated");
+            }
+            let body = new URLSearchParams();
+            body.append("category", self.currentTarget.dataset["category"]);
+            body.append("id", self.currentTarget.dataset["id"]);
+            body.append("donated", self.currentTarget.checked);
+            fetch("/api/donations", {method: "POST", body: body});
+        });
+    });
+};
+
+catalog();
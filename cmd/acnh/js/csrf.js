@@ -0,0 +1,35 @@
+// Sends the logged in session's CSRF token along with anything that changes
+// something, both from forms and from fetch.
+(function() {
+    function token() {
+        var match = document.cookie.match(/(?:^|;\s*)acnh_csrf=([^;]*)/);
+        return match ? decodeURIComponent(match[1]) : "";
+    }
+
+    var originalFetch = window.fetch;
+    window.fetch = function(resource, init) {
+        init = init || {};
+        var method = (init.method || "GET").toUpperCase();
+        if (method !== "GET" && method !== "HEAD" && token()) {
+            var headers = new Headers(init.headers || {});
+            headers.set("X-CSRF-Token", token());
+            init.headers = headers;
+        }
+        return originalFetch(resource, init);
+    };
+
+    document.addEventListener("submit", function(event) {
+        var form = event.target;
+        if (form.method.toLowerCase() !== "post" || !token()) {
+            return;
+        }
+        var input = form.querySelector("input[name=csrf_token]");
+        if (!input) {
+            input = document.createElement("input");
+            input.type = "hidden";
+            input.name = "csrf_token";
+            form.appendChild(input);
+        }
+        input.value = token();
+    }, true);
+})();
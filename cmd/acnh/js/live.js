@@ -0,0 +1,53 @@
+// Keeps the critter tables up to date without reloading: the server pushes
+// every critter's availability when the page opens and again on the hour,
+// and in between each row counts down to when it next arrives or leaves.
+let live = function() {
+    let rowClasses = {"bugs": "bug_row", "fishes": "fish_row", "sea_creatures": "sea_creature_row"};
+    let changes = new Map();
+    let offset = 0;
+    let leaves = document.body.dataset["leaves"] || "(leaves in %s)";
+    let arrives = document.body.dataset["arrives"] || "(arrives in %s)";
+
+    let events = new EventSource("/api/events" + window.location.search);
+    events.addEventListener("availability", function(e) {
+        let update = JSON.parse(e.data);
+        offset = new Date(update.time).getTime() - Date.now();
+        update.critters.forEach(function(critter) {
+            document.querySelectorAll("." + rowClasses[critter.category]).forEach(function(row) {
+                if (row.dataset["id"] !== critter.id) {
+                    return;
+                }
+                row.classList.toggle("available", critter.available_now);
+                row.classList.toggle("unavailable", !critter.available_now);
+                row.classList.toggle("out_of_season", critter.status === "out_of_season");
+                let cell = row.querySelector(".availability");
+                cell.querySelector(".timing").textContent = critter.display;
+                if (critter.changes) {
+                    changes.set(cell, {at: new Date(critter.changes).getTime(), leaves: critter.available_now});
+                } else {
+                    changes.delete(cell);
+                    cell.querySelector(".countdown").textContent = "";
+                }
+            });
+        });
+        tick();
+    });
+
+    function tick() {
+        let now = Date.now() + offset;
+        changes.forEach(function(change, cell) {
+            let left = Math.max(0, Math.floor((change.at - now) / 1000));
+            let text = left >= 86400 ? Math.floor(left / 86400) + "d " : "";
+            text += Math.floor(left % 86400 / 3600) + ":" + pad(Math.floor(left % 3600 / 60)) + ":" + pad(left % 60);
+            cell.querySelector(".countdown").textContent = (change.leaves ? leaves : arrives).replace("%s", text);
+        });
+    }
+
+    function pad(n) {
+        return n < 10 ? "0" + n : "" + n;
+    }
+
+    setInterval(tick, 1000);
+};
+
+live();
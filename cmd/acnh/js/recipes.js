@@ -0,0 +1,18 @@
+let recipes = function() {
+    document.querySelectorAll(".learned_checkbox").forEach(function(checkbox) {
+        checkbox.addEventListener("click", function(self) {
+            let row = self.currentTarget.closest("tr");
+            if (self.currentTarget.checked) {
+                row.classList.add("donated");
+            } else {
+                row.classList.remove("donated");
+            }
+            let body = new URLSearchParams();
+            body.append("id", self.currentTarget.dataset["id"]);
+            body.append("learned", self.currentTarget.checked);
+            fetch("/api/learned", {method: "POST", body: body});
+        });
+    });
+};
+
+recipes();
@@ -0,0 +1,30 @@
+let villagers = function() {
+    let errorElem = document.getElementById("resident_error");
+    document.querySelectorAll(".resident_checkbox").forEach(function(checkbox) {
+        checkbox.addEventListener("click", function(self) {
+            let box = self.currentTarget;
+            let row = box.closest("tr");
+            let body = new URLSearchParams();
+            body.append("id", box.dataset["id"]);
+            body.append("resident", box.checked);
+            fetch("/api/residents", {method: "POST", body: body}).then(function(response) {
+                if (!response.ok) {
+                    // Most likely a full island; put the checkbox back.
+                    box.checked = !box.checked;
+                    response.text().then(function(text) {
+                        errorElem.textContent = text;
+                    });
+                    return;
+                }
+                errorElem.textContent = "";
+                if (box.checked) {
+                    row.classList.add("resident");
+                } else {
+                    row.classList.remove("resident");
+                }
+            });
+        });
+    });
+};
+
+villagers();
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+// LiveCritter is one critter's row on the page as it should be right now.
+// Display is its timing in the page's language.  Changes is when it next
+// arrives or leaves, whichever's next, for counting down to; it's missing if
+// that's not within a year.
+type LiveCritter struct {
+	Category     string     `json:"category"`
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	AvailableNow bool       `json:"available_now"`
+	Status       string     `json:"status"`
+	Display      string     `json:"display"`
+	Changes      *time.Time `json:"changes,omitempty"`
+}
+
+// LiveUpdate is what gets pushed to the page: every critter, as of Time on
+// the island's clock.
+type LiveUpdate struct {
+	Time     time.Time     `json:"time"`
+	Critters []LiveCritter `json:"critters"`
+}
+
+// liveUpdate works out every critter's row at t, in language.  critters
+// should already be in the island's hemisphere.
+func liveUpdate(critters ACNH, language Language, t time.Time) LiveUpdate {
+	u := LiveUpdate{Time: t, Critters: []LiveCritter{}}
+	for _, c := range critters.critters() {
+		at := season.At(c.Months, c.HourMap, t)
+		lc := LiveCritter{
+			Category:     c.Category,
+			ID:           c.ID,
+			Name:         language.CritterName(c.Names, c.Name),
+			AvailableNow: at.AvailableNow,
+			Status:       at.Status,
+			Display:      language.Timing(at),
+		}
+		if w := season.Next(c.Months, c.HourMap, t); w != nil {
+			if at.AvailableNow {
+				lc.Changes = w.End
+			} else {
+				lc.Changes = &w.Start
+			}
+		}
+		u.Critters = append(u.Critters, lc)
+	}
+	return u
+}
+
+// nextHour is the top of the hour after t, on t's clock.
+func nextHour(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(time.Hour)
+}
+
+// liveKeepAlive is how often an idle stream gets a comment, so proxies
+// don't give up on it between hours.
+const liveKeepAlive = 30 * time.Second
+
+// liveHandler streams LiveUpdates as server-sent events: one straight away,
+// then another every time the island's clock reaches a new hour.
+func liveHandler(critters ACNH, languages Languages, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming isn't supported", http.StatusInternalServerError)
+			return
+		}
+		id := store.profile(w, r)
+		c := critters.forHemisphere(hemisphereFor(r, store, id))
+		language := languageFor(r, languages, store, id)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+
+		keepAlive := time.NewTicker(liveKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			t, err := store.Now(id)
+			if err != nil {
+				logger.Log("failed loading timezone data", "error", err)
+				return
+			}
+			b, err := json.Marshal(liveUpdate(c, language, t))
+			if err != nil {
+				logger.Log("failed encoding live update", "error", err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: availability\ndata: %s\n\n", b); err != nil {
+				return
+			}
+			flusher.Flush()
+
+			hour := time.NewTimer(nextHour(t).Sub(t))
+		wait:
+			for {
+				select {
+				case <-r.Context().Done():
+					hour.Stop()
+					return
+				case <-keepAlive.C:
+					if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+						hour.Stop()
+						return
+					}
+					flusher.Flush()
+				case <-hour.C:
+					break wait
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveUpdate(t *testing.T) {
+	night := time.Date(2020, time.January, 5, 21, 30, 0, 0, time.UTC)
+	u := liveUpdate(slashCritters(), english, night)
+	if len(u.Critters) != 2 {
+		t.Fatalf("expected every critter, got %+v", u.Critters)
+	}
+	tarantula := u.Critters[0]
+	if !tarantula.AvailableNow || tarantula.Display != "Until 4AM" || !tarantula.Changes.Equal(time.Date(2020, time.January, 6, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the tarantula to leave at 4AM, got %+v", tarantula)
+	}
+	if coelacanth := u.Critters[1]; coelacanth.Display != "All Day" || coelacanth.Changes != nil {
+		t.Errorf("expected the coelacanth to never leave, got %+v", coelacanth)
+	}
+
+	u = liveUpdate(slashCritters(), english, time.Date(2020, time.May, 5, 12, 0, 0, 0, time.UTC))
+	if tarantula := u.Critters[0]; tarantula.Status != StatusOutOfSeason || !tarantula.Changes.Equal(time.Date(2020, time.November, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the tarantula to arrive in November, got %+v", tarantula)
+	}
+}
+
+func TestNextHour(t *testing.T) {
+	india := time.FixedZone("IST", 5*60*60+30*60)
+	got := nextHour(time.Date(2020, time.May, 5, 12, 40, 10, 0, india))
+	if want := time.Date(2020, time.May, 5, 13, 0, 0, 0, india); !got.Equal(want) {
+		t.Errorf("nextHour = %v, want %v", got, want)
+	}
+}
+
+func TestLiveHandler(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	server := httptest.NewServer(liveHandler(slashCritters(), Languages{"en": english}, store, nopLogger{}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected an event stream, got %q", ct)
+	}
+	lines := bufio.NewScanner(resp.Body)
+	var event, data string
+	for lines.Scan() && lines.Text() != "" {
+		if strings.HasPrefix(lines.Text(), "event: ") {
+			event = strings.TrimPrefix(lines.Text(), "event: ")
+		}
+		if strings.HasPrefix(lines.Text(), "data: ") {
+			data = strings.TrimPrefix(lines.Text(), "data: ")
+		}
+	}
+	var u LiveUpdate
+	if err := json.Unmarshal([]byte(data), &u); err != nil || event != "availability" || len(u.Critters) != 2 {
+		t.Errorf("expected an update straight away, got %q %q: %v", event, data, err)
+	}
+}
@@ -3,22 +3,33 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"html/template"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
-	"text/template"
+	"strings"
 	"time"
+
+	"github.com/swerveaux/acnh/season"
 )
 
 type Bug struct {
-	Name     string `json:"name"`
-	Price    int    `json:"price"`
-	Months   []int  `json:"months"`
-	Hours    []int  `json:"hours"`
-	Location string `json:"location"`
-	HourMap  map[int]bool
-	Timing   Timing
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Price       int               `json:"price"`
+	Months      []int             `json:"months"`
+	Hours       []int             `json:"hours"`
+	Location    string            `json:"location"`
+	Weather     string            `json:"weather,omitempty"`
+	Rarity      int               `json:"rarity,omitempty"`
+	Requirement string            `json:"requirement,omitempty"`
+	HardToCatch bool              `json:"hard_to_catch"`
+	Names       map[string]string `json:"names,omitempty"`
+	Aliases     []string          `json:"aliases,omitempty"`
+	HourMap     map[int]bool      `json:"-"`
+	Timing      Timing            `json:"timing"`
 }
 
 func (b *Bug) SetHourMap(m map[int]bool) {
@@ -30,30 +41,22 @@ func (b *Bug) GetHours() []int {
 }
 
 type Fish struct {
-	Name       string `json:"name"`
-	Price      int    `json:"price"`
-	Months     []int  `json:"months"`
-	Hours      []int  `json:"hours"`
-	Location   string `json:"location"`
-	ShadowSize string `json:"shadow_size"`
-	HourMap    map[int]bool
-	Timing     Timing
-}
-
-type Umbrella struct {
-	Name               string `json:"name"`
-	DIY                string `json:"diy"`
-	BuyPrice           string `json:"buy_price"`
-	SellPrice          string `json:"sell_price"`
-	HHABase            string `json:"hha_base"`
-	Color1             string `json:"color_1"`
-	Color2             string `json:"color_2"`
-	Size               string `json:"size"`
-	MilesPrice         string `json:"miles_price"`
-	Source             string `json:"source"`
-	SourceNotes        string `json:"source_notes"`
-	VillagerEquippable string `json:"villager_equippable"`
-	Catalog            string `json:"catalog"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Price       int               `json:"price"`
+	Months      []int             `json:"months"`
+	Hours       []int             `json:"hours"`
+	Location    string            `json:"location"`
+	ShadowSize  string            `json:"shadow_size"`
+	Fin         bool              `json:"fin"`
+	Weather     string            `json:"weather,omitempty"`
+	Rarity      int               `json:"rarity,omitempty"`
+	Requirement string            `json:"requirement,omitempty"`
+	HardToCatch bool              `json:"hard_to_catch"`
+	Names       map[string]string `json:"names,omitempty"`
+	Aliases     []string          `json:"aliases,omitempty"`
+	HourMap     map[int]bool      `json:"-"`
+	Timing      Timing            `json:"timing"`
 }
 
 func (f *Fish) SetHourMap(m map[int]bool) {
@@ -65,12 +68,20 @@ func (f *Fish) GetHours() []int {
 }
 
 type SeaCreature struct {
-	Name    string `json:"name"`
-	Price   int    `json:"price"`
-	Hours   []int  `json:"hours"`
-	Months  []int  `json:"months"`
-	HourMap map[int]bool
-	Timing  Timing
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Price       int               `json:"price"`
+	Hours       []int             `json:"hours"`
+	Months      []int             `json:"months"`
+	ShadowSize  string            `json:"shadow_size,omitempty"`
+	Speed       string            `json:"speed,omitempty"`
+	Rarity      int               `json:"rarity,omitempty"`
+	Requirement string            `json:"requirement,omitempty"`
+	HardToCatch bool              `json:"hard_to_catch"`
+	Names       map[string]string `json:"names,omitempty"`
+	Aliases     []string          `json:"aliases,omitempty"`
+	HourMap     map[int]bool      `json:"-"`
+	Timing      Timing            `json:"timing"`
 }
 
 func (s *SeaCreature) SetHourMap(m map[int]bool) {
@@ -87,44 +98,75 @@ type HourMapper interface {
 }
 
 type ACNH struct {
-	Bugs         []Bug         `json:"bugs"`
-	Fishes       []Fish        `json:"fishes"`
-	SeaCreatures []SeaCreature `json:"sea_creatures"`
-	Umbrellas    []Umbrella    `json:"umbrellas"`
+	Bugs         []Bug             `json:"bugs"`
+	Fishes       []Fish            `json:"fishes"`
+	SeaCreatures []SeaCreature     `json:"sea_creatures"`
+	Fossils      []Fossil          `json:"fossils"`
+	Art          []Art             `json:"art"`
+	Catalog      []CatalogCategory `json:"catalog"`
+	Villagers    []Villager        `json:"villagers"`
+	Recipes      []Recipe          `json:"recipes"`
+	Materials    []Material        `json:"materials"`
 }
 
-type Timing struct {
-	AvailableNow    bool
-	AvailableAt     int
-	AvailableUntil  int
-	AvailableAllDay bool
-	CurrentHour     int
+type Timing = season.Timing
+
+// Statuses a critter can have, from most to least available.
+const (
+	StatusNow         = season.StatusNow
+	StatusLaterToday  = season.StatusLaterToday
+	StatusThisMonth   = season.StatusThisMonth
+	StatusOutOfSeason = season.StatusOutOfSeason
+)
+
+// shadowSizes and speeds are the normalized values the loader writes out, in
+// order from smallest/slowest.
+var shadowSizes = []string{"Tiny", "Small", "Medium", "Large", "XL", "XXL", "Narrow"}
+var speeds = []string{"Stationary", "Very slow", "Slow", "Medium", "Fast", "Very fast"}
+
+// CritterFilter narrows down the critters shown on the page or returned from
+// the API.  Empty fields match everything.  Shadow size only applies to
+// fish and sea creatures, and speed only to sea creatures, so bugs (and fish,
+// for speed) are left out entirely when those are set.
+type CritterFilter struct {
+	ShadowSize string `json:"shadow_size,omitempty"`
+	Speed      string `json:"speed,omitempty"`
+	Fin        bool   `json:"fin,omitempty"`
+	// All includes critters that are out of season.
+	All bool `json:"all,omitempty"`
 }
 
-func (t *Timing) DisplayAt() string {
-	if t.AvailableAt == 0 {
-		return "12AM"
+func parseCritterFilter(q url.Values) CritterFilter {
+	return CritterFilter{
+		ShadowSize: q.Get("shadow"),
+		Speed:      q.Get("speed"),
+		Fin:        q.Get("fin") == "true" || q.Get("fin") == "on",
+		All:        q.Get("all") == "true" || q.Get("all") == "on",
 	}
-	if t.AvailableAt == 12 {
-		return "12PM"
+}
+
+func (f CritterFilter) matchesFish(fish Fish) bool {
+	if f.Speed != "" {
+		return false
 	}
-	if t.AvailableAt > 12 {
-		return fmt.Sprintf("%dPM", t.AvailableAt-12)
+	if f.ShadowSize != "" && !strings.EqualFold(f.ShadowSize, fish.ShadowSize) {
+		return false
 	}
-	return fmt.Sprintf("%dAM", t.AvailableAt)
+	return !f.Fin || fish.Fin
 }
 
-func (t *Timing) DisplayUntil() string {
-	if t.AvailableUntil == 0 {
-		return "12AM"
+func (f CritterFilter) matchesSeaCreature(sc SeaCreature) bool {
+	if f.Fin {
+		return false
 	}
-	if t.AvailableUntil == 12 {
-		return "12PM"
+	if f.ShadowSize != "" && !strings.EqualFold(f.ShadowSize, sc.ShadowSize) {
+		return false
 	}
-	if t.AvailableUntil > 12 {
-		return fmt.Sprintf("%dPM", t.AvailableUntil-12)
-	}
-	return fmt.Sprintf("%dAM", t.AvailableUntil)
+	return f.Speed == "" || strings.EqualFold(f.Speed, sc.Speed)
+}
+
+func (f CritterFilter) matchesBug(bug Bug) bool {
+	return f.ShadowSize == "" && f.Speed == "" && !f.Fin
 }
 
 func main() {
@@ -134,17 +176,134 @@ func main() {
 		log.Fatal(err)
 	}
 
-	tmpl, err := loadTemplate(logger)
+	tmpl, err := loadTemplate("main", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	catalogTmpl, err := loadTemplate("catalog", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	villagersTmpl, err := loadTemplate("villagers", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	recipesTmpl, err := loadTemplate("recipes", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	turnipsTmpl, err := loadTemplate("turnips", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rankingTmpl, err := loadTemplate("ranking", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	routeTmpl, err := loadTemplate("route", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	searchTmpl, err := loadTemplate("search", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	islandsTmpl, err := loadTemplate("islands", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	groupsTmpl, err := loadTemplate("groups", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	critterTmpl, err := loadTemplate("critter", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	accountTmpl, err := loadTemplate("account", logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	languages, err := loadLanguages("i18n")
+	if err != nil {
+		log.Fatal(err)
+	}
+	store, err := loadStore("store.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	missing, err := store.makeAdmins(adminUsernames(os.Getenv("ACNH_ADMINS")))
 	if err != nil {
 		log.Fatal(err)
 	}
+	for _, username := range missing {
+		logger.Log("no account to make an admin", "username", username)
+	}
+	if err := store.renameKeys(critters.keyRenames()); err != nil {
+		log.Fatal(err)
+	}
 
-	http.HandleFunc("/", mainHandler(critters, tmpl, logger))
+	http.HandleFunc("/", mainHandler(critters, languages, store, tmpl, logger))
+	http.HandleFunc("/api/critters", apiHandler(critters, store, logger))
+	http.HandleFunc("/api/events", liveHandler(critters, languages, store, logger))
+	http.HandleFunc("/api/language", languageHandler(languages, store, logger))
+	http.HandleFunc("/api/stats", statsHandler(critters, store, logger))
+	http.HandleFunc("/islands", islandsHandler(languages, store, islandsTmpl, logger))
+	http.HandleFunc("/api/islands", islandsAPIHandler(store, logger))
+	http.HandleFunc("/api/islands/current", currentIslandHandler(store, logger))
+	http.HandleFunc("/api/donations", donationsHandler(critters, store, logger))
+	http.HandleFunc("/api/leaving", leavingHandler(critters, store, logger))
+	http.HandleFunc("/catalog", catalogHandler(critters.Catalog, languages, store, catalogTmpl, logger))
+	http.HandleFunc("/api/catalog", catalogAPIHandler(critters.Catalog, store, logger))
+	http.HandleFunc("/catalog.js", fileHandler("js/catalog.js", "application/javascript", logger))
+	http.HandleFunc("/villagers", villagersHandler(critters.Villagers, languages, store, villagersTmpl, logger))
+	http.HandleFunc("/villagers.js", fileHandler("js/villagers.js", "application/javascript", logger))
+	http.HandleFunc("/api/residents", residentsHandler(critters, store, logger))
+	http.HandleFunc("/recipes", recipesHandler(critters, languages, store, recipesTmpl, logger))
+	http.HandleFunc("/recipes.js", fileHandler("js/recipes.js", "application/javascript", logger))
+	http.HandleFunc("/api/planner", plannerHandler(critters, store, logger))
+	http.HandleFunc("/api/learned", learnedHandler(critters, store, logger))
+	http.HandleFunc("/turnips", turnipsHandler(languages, store, turnipsTmpl, logger))
+	http.HandleFunc("/api/turnips", turnipsAPIHandler(store, logger))
+	http.HandleFunc("/ranking", rankingHandler(critters, languages, store, rankingTmpl, logger))
+	http.HandleFunc("/api/ranking", rankingAPIHandler(critters, store, logger))
+	http.HandleFunc("/route", routeHandler(critters, languages, store, routeTmpl, logger))
+	http.HandleFunc("/api/route", routeAPIHandler(critters, store, logger))
+	http.HandleFunc("/search", searchHandler(critters, languages, store, searchTmpl, logger))
+	http.HandleFunc("/api/search", searchAPIHandler(critters, store, logger))
+	http.HandleFunc("/critter/", critterHandler(critters, languages, store, critterTmpl, logger))
+	http.HandleFunc("/api/critter/", critterAPIHandler(critters, store, logger))
+	http.HandleFunc("/api/slash/", slashHandler(critters, slashPlatforms(), logger))
+	http.HandleFunc("/api/webhooks", webhooksHandler(store, logger))
+	http.HandleFunc("/feed.atom", feedHandler(critters, logger))
+	http.HandleFunc("/calendar.ics", calendarHandler(critters, store, logger))
+	http.HandleFunc("/groups", groupsHandler(critters, languages, store, groupsTmpl, logger))
+	http.HandleFunc("/api/groups", groupsAPIHandler(store, logger))
+	http.HandleFunc("/api/groups/board", groupBoardHandler(critters, store, logger))
+	http.HandleFunc("/account", accountHandler(languages, store, accountTmpl, logger))
+	http.HandleFunc("/login/token", loginTokenHandler(store, logger))
+	http.HandleFunc("/api/account", accountAPIHandler(store, logger))
+	http.HandleFunc("/api/admin/users", requireAdmin(store, adminUsersHandler(store, logger)))
+	http.HandleFunc("/api/admin/export", requireAdmin(store, adminExportHandler(store, logger)))
+	http.HandleFunc("/csrf.js", fileHandler("js/csrf.js", "application/javascript", logger))
 	http.HandleFunc("/sortable.js", sortableHandler(logger))
 	http.HandleFunc("/style.css", cssHandler(logger))
 	http.HandleFunc("/acnh.js", jsHandler(logger))
+	http.HandleFunc("/live.js", fileHandler("js/live.js", "application/javascript", logger))
+
+	go newWebhookScheduler(critters, store, logger).run()
+
 	logger.Log("Starting server", "port", "80")
-	log.Fatal(http.ListenAndServe(":80", nil))
+	log.Fatal(http.ListenAndServe(":80", csrfProtect(store, checkAPIToken(store, http.DefaultServeMux))))
 }
 
 func sortableHandler(logger Logger) http.HandlerFunc {
@@ -208,47 +367,145 @@ func jsHandler(logger Logger) http.HandlerFunc {
 	}
 }
 
-func mainHandler(critters ACNH, tmpl *template.Template, logger Logger) http.HandlerFunc {
+// Page is what gets handed to the main template.
+type Page struct {
+	ACNH
+	Filter      CritterFilter
+	ShadowSizes []string
+	Speeds      []string
+	Hemisphere  Hemisphere
+	Islands     []IslandSettings
+	FossilSets  []FossilSet
+	Birthdays   []Birthday
+	LeavingSoon []Critter
+	Progress    []Progress
+	// Language is the code of the language the user picked, if they have,
+	// and Languages are the ones they can pick from.
+	Language  string
+	Languages []Language
+}
+
+// fileHandler serves a static file, reading it fresh each time so it can be
+// tweaked without restarting the server.
+func fileHandler(path, contentType string, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			logger.Log("failed to read static file", "path", path, "error", err)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(b)
+	}
+}
+
+func mainHandler(critters ACNH, languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if tmpl == nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		loc, err := time.LoadLocation("America/Los_Angeles")
+		id := store.profile(w, r)
+		t, err := store.Now(id)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprintln(w, "the Los Angeles time zone no longer exists...that's bad.")
+			fmt.Fprintln(w, "your island's time zone no longer exists...that's bad.")
 			logger.Log("failed loading timezone data", "error", err)
 			return
 		}
-		t := time.Now().In(loc)
-		var filteredCritters ACNH
-
-		for _, bug := range critters.Bugs {
-			if contains(bug.Months, int(t.Month())-1) {
-				b := bug
-				b.Timing = timing(bug.HourMap, t.Hour())
-				filteredCritters.Bugs = append(filteredCritters.Bugs, b)
-			}
+
+		hemisphere := hemisphereFor(r, store, id)
+		c := critters.forHemisphere(hemisphere)
+		filter := parseCritterFilter(r.URL.Query())
+		filteredCritters := availableCritters(c, t, filter)
+		filteredCritters.Fossils = critters.Fossils
+		filteredCritters.Art = critters.Art
+
+		donated := store.AllDonated(id)
+		page := Page{
+			ACNH:        filteredCritters,
+			Filter:      filter,
+			ShadowSizes: shadowSizes,
+			Speeds:      speeds,
+			Hemisphere:  hemisphere,
+			Islands:     store.Islands(id),
+			Language:    store.Language(id),
+			Languages:   languages.sorted(),
+			FossilSets:  fossilSets(critters.Fossils, store.Donated(id, "fossils")),
+			LeavingSoon: rareLeavingSoon(c, donated, t),
+			Progress:    museumProgress(c, donated, t),
+			Birthdays:   birthdaysThisMonth(critters.Villagers, store.Residents(id), t),
 		}
-		for _, fish := range critters.Fishes {
-			if contains(fish.Months, int(t.Month())-1) {
-				fish.Timing = timing(fish.HourMap, t.Hour())
-				filteredCritters.Fishes = append(filteredCritters.Fishes, fish)
-			}
+		if err := render(w, r, languages, store, id, tmpl, http.StatusOK, page); err != nil {
+			logger.Log("failed rendering main page", "error", err)
 		}
-		for _, sc := range critters.SeaCreatures {
-			if contains(sc.Months, int(t.Month())-1) {
-				sc.Timing = timing(sc.HourMap, t.Hour())
-				filteredCritters.SeaCreatures = append(filteredCritters.SeaCreatures, sc)
-			}
+	}
+}
+
+func apiHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		t, err := store.Now(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			logger.Log("failed loading timezone data", "error", err)
+			return
 		}
 
-		filteredCritters.Umbrellas = critters.Umbrellas
+		c := critters.forHemisphere(hemisphereFor(r, store, id))
+		filteredCritters := availableCritters(c, t, parseCritterFilter(r.URL.Query()))
+		if r.URL.Query().Get("sort") == "rarity" {
+			sortByRarity(&filteredCritters)
+		}
+		writeJSON(w, filteredCritters, logger)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}, logger Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Log("failed encoding JSON response", "error", err)
+	}
+}
+
+// now returns the current time in the island's time zone.
+func now(timezone string) (time.Time, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(loc), nil
+}
 
-		tmpl.Execute(w, filteredCritters)
+// availableCritters returns the critters available during t's month that
+// match filter, with their Timing filled in for t.  If the filter asks for
+// all of them, out of season critters are included too.
+func availableCritters(critters ACNH, t time.Time, filter CritterFilter) ACNH {
+	var filteredCritters ACNH
+
+	for _, bug := range critters.Bugs {
+		if (filter.All || contains(bug.Months, int(t.Month())-1)) && filter.matchesBug(bug) {
+			b := bug
+			b.Timing = season.At(bug.Months, bug.HourMap, t)
+			filteredCritters.Bugs = append(filteredCritters.Bugs, b)
+		}
+	}
+	for _, fish := range critters.Fishes {
+		if (filter.All || contains(fish.Months, int(t.Month())-1)) && filter.matchesFish(fish) {
+			fish.Timing = season.At(fish.Months, fish.HourMap, t)
+			filteredCritters.Fishes = append(filteredCritters.Fishes, fish)
+		}
+	}
+	for _, sc := range critters.SeaCreatures {
+		if (filter.All || contains(sc.Months, int(t.Month())-1)) && filter.matchesSeaCreature(sc) {
+			sc.Timing = season.At(sc.Months, sc.HourMap, t)
+			filteredCritters.SeaCreatures = append(filteredCritters.SeaCreatures, sc)
+		}
 	}
+
+	return filteredCritters
 }
 
 func loadCritters(logger Logger) (ACNH, error) {
@@ -261,28 +518,28 @@ func loadCritters(logger Logger) (ACNH, error) {
 	err = json.NewDecoder(file).Decode(&critters)
 	for i := range critters.Bugs {
 		setHourMap(&critters.Bugs[i])
+		critters.Bugs[i].HardToCatch = hardToCatch(critters.Bugs[i].Rarity, critters.Bugs[i].Weather)
 	}
 	for i := range critters.Fishes {
 		setHourMap(&critters.Fishes[i])
+		critters.Fishes[i].HardToCatch = hardToCatch(critters.Fishes[i].Rarity, critters.Fishes[i].Weather)
 	}
 	for i := range critters.SeaCreatures {
 		setHourMap(&critters.SeaCreatures[i])
+		critters.SeaCreatures[i].HardToCatch = hardToCatch(critters.SeaCreatures[i].Rarity, "")
 	}
 	return critters, err
 }
 
 func setHourMap(h HourMapper) {
-	hours := h.GetHours()
-	hourMap := make(map[int]bool)
-	for i := range hours {
-		hourMap[hours[i]] = true
-	}
-
-	h.SetHourMap(hourMap)
+	h.SetHourMap(season.HourMap(h.GetHours()))
 }
 
-func loadTemplate(logger Logger) (*template.Template, error) {
-	file, err := os.Open("templates/main.html")
+// loadTemplate parses a template from the templates directory.  It can use
+// the funcs for showing things in other languages, which are English's
+// unless it's run with Language.execute.
+func loadTemplate(name string, logger Logger) (*template.Template, error) {
+	file, err := os.Open("templates/" + name + ".html")
 	if err != nil {
 		return nil, err
 	}
@@ -291,7 +548,7 @@ func loadTemplate(logger Logger) (*template.Template, error) {
 	if err != nil {
 		return nil, err
 	}
-	return template.New("main").Parse(string(text))
+	return template.New(name).Funcs(english.funcs()).Parse(string(text))
 }
 
 func contains(s []int, n int) bool {
@@ -302,39 +559,3 @@ func contains(s []int, n int) bool {
 	}
 	return false
 }
-
-func timing(s map[int]bool, n int) Timing {
-	if len(s) == 24 {
-		return Timing{
-			AvailableNow:    true,
-			AvailableAllDay: true,
-			CurrentHour:     n,
-		}
-	}
-
-	availableNow := s[n]
-	var availableUntil int
-	var availableAt int
-	if availableNow {
-		for i := n; i < n+24; i++ {
-			if !s[i%24] {
-				availableUntil = i % 24
-				break
-			}
-		}
-	} else {
-		for i := n; i < n+24; i++ {
-			if s[i%24] {
-				availableAt = i % 24
-				break
-			}
-		}
-	}
-
-	return Timing{
-		AvailableNow:   availableNow,
-		AvailableUntil: availableUntil,
-		AvailableAt:    availableAt,
-		CurrentHour:    n,
-	}
-}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Fossil struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Set     string   `json:"set"`
+	Price   int      `json:"price"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+type Art struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Title      string   `json:"title"`
+	Artist     string   `json:"artist"`
+	Type       string   `json:"type"`
+	HasForgery bool     `json:"has_forgery"`
+	Notes      string   `json:"notes"`
+	Aliases    []string `json:"aliases,omitempty"`
+}
+
+// FossilSet groups the pieces of a multi-part fossil together.  Standalone
+// fossils are a set of one.
+type FossilSet struct {
+	Name    string   `json:"name"`
+	Parts   []Fossil `json:"parts"`
+	Price   int      `json:"price"`
+	Donated int      `json:"donated"`
+}
+
+func (f FossilSet) Complete() bool {
+	return f.Donated == len(f.Parts)
+}
+
+// fossilSets groups fossils by set, keeping the order sets first show up in,
+// and counts how many parts of each have been donated.
+func fossilSets(fossils []Fossil, donated map[string]bool) []FossilSet {
+	var sets []FossilSet
+	index := make(map[string]int)
+	for _, fossil := range fossils {
+		i, ok := index[fossil.Set]
+		if !ok {
+			i = len(sets)
+			index[fossil.Set] = i
+			sets = append(sets, FossilSet{Name: fossil.Set})
+		}
+		sets[i].Parts = append(sets[i].Parts, fossil)
+		sets[i].Price += fossil.Price
+		if donated[fossil.ID] {
+			sets[i].Donated++
+		}
+	}
+	return sets
+}
+
+// museumEntry is anything that gets tracked, with the ID it's tracked under
+// and the names it's gone by.
+type museumEntry struct {
+	category string
+	id       string
+	name     string
+	aliases  []string
+}
+
+// museum returns everything that can be donated to the museum.
+func (a ACNH) museum() []museumEntry {
+	var entries []museumEntry
+	for _, c := range a.critters() {
+		entries = append(entries, museumEntry{c.Category, c.ID, c.Name, c.Aliases})
+	}
+	for _, f := range a.Fossils {
+		entries = append(entries, museumEntry{"fossils", f.ID, f.Name, f.Aliases})
+	}
+	for _, art := range a.Art {
+		entries = append(entries, museumEntry{"art", art.ID, art.Name, art.Aliases})
+	}
+	return entries
+}
+
+// entries returns everything that gets tracked: the museum, catalog items
+// by the catalog category's ID, "villagers" and "recipes".
+func (a ACNH) entries() []museumEntry {
+	entries := a.museum()
+	for _, c := range a.Catalog {
+		for _, item := range c.Items {
+			entries = append(entries, museumEntry{c.ID, item.ID, item.Name, item.Aliases})
+		}
+	}
+	for _, v := range a.Villagers {
+		entries = append(entries, museumEntry{"villagers", v.ID, v.Name, v.Aliases})
+	}
+	for _, r := range a.Recipes {
+		entries = append(entries, museumEntry{"recipes", r.ID, r.Name, r.Aliases})
+	}
+	return entries
+}
+
+// hasEntry reports whether id is something in the given category of
+// entries, so that people can't stuff arbitrary junk into the store.
+func (a ACNH) hasEntry(category, id string) bool {
+	for _, e := range a.entries() {
+		if e.category == category && e.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// hasItem reports whether key is something in the museum or the catalog.
+// Residents and recipes have their own places in the store.
+func (a ACNH) hasItem(category, key string) bool {
+	if category == "villagers" || category == "recipes" {
+		return false
+	}
+	return a.hasEntry(category, key)
+}
+
+// donationKey is what the named thing in category is tracked under: its ID,
+// found by its name now or one it used to have, or else the name itself.
+func (a ACNH) donationKey(category, name string) string {
+	for _, e := range a.entries() {
+		if e.category != category {
+			continue
+		}
+		if e.name == name {
+			return e.id
+		}
+		for _, alias := range e.aliases {
+			if alias == name {
+				return e.id
+			}
+		}
+	}
+	return name
+}
+
+// keyRenames maps each entry's names, now and before, to its ID, by
+// category, for moving donations, residents and learned recipes from before
+// there were IDs over to them.
+func (a ACNH) keyRenames() map[string]map[string]string {
+	renames := make(map[string]map[string]string)
+	for _, e := range a.entries() {
+		if renames[e.category] == nil {
+			renames[e.category] = make(map[string]string)
+		}
+		for _, name := range append([]string{e.name}, e.aliases...) {
+			if name != e.id {
+				renames[e.category][name] = e.id
+			}
+		}
+	}
+	return renames
+}
+
+// donationsHandler returns everything the user has donated on GET, and marks
+// a single thing donated (or not) on POST.  Museum entries are posted by id,
+// but a name is still understood for clients from before there were IDs.
+func donationsHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, store.AllDonated(id), logger)
+		case http.MethodPost:
+			category := r.FormValue("category")
+			key := r.FormValue("id")
+			if key == "" {
+				key = critters.donationKey(category, r.FormValue("name"))
+			}
+			if !critters.hasItem(category, key) {
+				http.Error(w, "unknown category or name", http.StatusBadRequest)
+				return
+			}
+			donated, err := strconv.ParseBool(r.FormValue("donated"))
+			if err != nil {
+				http.Error(w, "donated must be true or false", http.StatusBadRequest)
+				return
+			}
+			if err := store.SetDonated(id, category, key, donated); err != nil {
+				logger.Log("failed saving donation", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// donatedSets turns what Store.AllDonated returns back into sets, for
+// looking things up by category and ID.
+func donatedSets(donated map[string][]string) map[string]map[string]bool {
+	sets := make(map[string]map[string]bool)
+	for category, names := range donated {
+		sets[category] = make(map[string]bool)
+		for _, name := range names {
+			sets[category][name] = true
+		}
+	}
+	return sets
+}
+
+// Progress is how much of one museum wing has been donated.  The rest is
+// only filled in for critters, which come and go: what's left to donate this
+// month, how much of what's left can still be caught before the year is out
+// and what it would sell for, and what won't be back until next year.
+type Progress struct {
+	Category           string   `json:"category"`
+	Name               string   `json:"name"`
+	Donated            int      `json:"donated"`
+	Total              int      `json:"total"`
+	Seasonal           bool     `json:"seasonal"`
+	RemainingThisMonth int      `json:"remaining_this_month"`
+	CatchableThisYear  int      `json:"catchable_this_year"`
+	RemainingValue     int      `json:"remaining_value"`
+	NotAgainThisYear   []string `json:"not_again_this_year"`
+}
+
+func (p Progress) Percent() int {
+	if p.Total == 0 {
+		return 0
+	}
+	return p.Donated * 100 / p.Total
+}
+
+// museumProgress counts what's been donated in each wing, in or out of
+// season, and what's left for the rest of t's year.  critters should already
+// be in the user's hemisphere.
+func museumProgress(critters ACNH, donated map[string][]string, t time.Time) []Progress {
+	done := donatedSets(donated)
+	progress := []Progress{
+		{Category: "bugs", Name: "Bugs", Total: len(critters.Bugs), Seasonal: true},
+		{Category: "fishes", Name: "Fish", Total: len(critters.Fishes), Seasonal: true},
+		{Category: "sea_creatures", Name: "Sea creatures", Total: len(critters.SeaCreatures), Seasonal: true},
+		{Category: "fossils", Name: "Fossils", Total: len(critters.Fossils)},
+		{Category: "art", Name: "Art", Total: len(critters.Art)},
+	}
+	byCategory := make(map[string]*Progress)
+	for i := range progress {
+		progress[i].NotAgainThisYear = []string{}
+		byCategory[progress[i].Category] = &progress[i]
+		for key := range done[progress[i].Category] {
+			if critters.hasItem(progress[i].Category, key) {
+				progress[i].Donated++
+			}
+		}
+	}
+
+	month := int(t.Month()) - 1
+	for _, c := range critters.critters() {
+		if done[c.Category][c.ID] {
+			continue
+		}
+		p := byCategory[c.Category]
+		if contains(c.Months, month) {
+			p.RemainingThisMonth++
+		}
+		catchable := false
+		for m := month; m < 12; m++ {
+			if contains(c.Months, m) {
+				catchable = true
+				break
+			}
+		}
+		if catchable {
+			p.CatchableThisYear++
+			p.RemainingValue += c.Price
+		} else {
+			p.NotAgainThisYear = append(p.NotAgainThisYear, c.Name)
+		}
+	}
+	return progress
+}
+
+func statsHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		t, err := store.Now(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			logger.Log("failed loading timezone data", "error", err)
+			return
+		}
+
+		c := critters.forHemisphere(hemisphereFor(r, store, id))
+		writeJSON(w, museumProgress(c, store.AllDonated(id), t), logger)
+	}
+}
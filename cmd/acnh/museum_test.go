@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMuseumProgress(t *testing.T) {
+	critters := ACNH{
+		Bugs: []Bug{
+			{ID: "donated-bug", Name: "donated bug", Price: 100, Months: []int{9}},
+			{ID: "spring-bug", Name: "spring bug", Price: 200, Months: []int{2, 3}},
+			{ID: "fall-bug", Name: "fall bug", Price: 300, Months: []int{8, 9}},
+			{ID: "winter-bug", Name: "winter bug", Price: 400, Months: []int{11, 0}},
+		},
+		Fishes: []Fish{
+			{ID: "fish", Name: "fish", Price: 1000, Months: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+		},
+		Fossils: []Fossil{{ID: "amber", Name: "amber"}, {ID: "ammonite", Name: "ammonite"}},
+	}
+	donated := map[string][]string{
+		"bugs":    {"donated-bug", "not-a-bug"},
+		"fossils": {"amber"},
+	}
+	october := time.Date(2020, time.October, 1, 12, 0, 0, 0, time.UTC)
+
+	progress := museumProgress(critters, donated, october)
+
+	bugs := progress[0]
+	if bugs.Donated != 1 || bugs.Total != 4 {
+		t.Errorf("expected 1/4 bugs donated, got %d/%d", bugs.Donated, bugs.Total)
+	}
+	if bugs.RemainingThisMonth != 1 {
+		t.Errorf("expected 1 bug left this month, got %d", bugs.RemainingThisMonth)
+	}
+	if bugs.CatchableThisYear != 2 || bugs.RemainingValue != 700 {
+		t.Errorf("expected 2 bugs worth 700 left this year, got %d worth %d", bugs.CatchableThisYear, bugs.RemainingValue)
+	}
+	if len(bugs.NotAgainThisYear) != 1 || bugs.NotAgainThisYear[0] != "spring bug" {
+		t.Errorf("expected spring bug to be gone until next year, got %v", bugs.NotAgainThisYear)
+	}
+
+	south := museumProgress(critters.forHemisphere(Southern), donated, october)
+	if south[0].RemainingThisMonth != 1 || south[0].NotAgainThisYear[0] != "fall bug" {
+		t.Errorf("expected the southern hemisphere to have spring and fall swapped, got %+v", south[0])
+	}
+
+	fossils := progress[3]
+	if fossils.Donated != 1 || fossils.Total != 2 || fossils.Seasonal {
+		t.Errorf("expected 1/2 fossils and no seasons, got %+v", fossils)
+	}
+}
+
+func TestDonationsByID(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	critters := slashCritters()
+	critters.Fossils = []Fossil{{ID: "ankylo-skul", Name: "Ankylo skull", Aliases: []string{"Ankylo skul"}}}
+	critters.Villagers = []Villager{{ID: "sherbet", Name: "Sherb", Aliases: []string{"Sherbet"}}}
+	critters.Recipes = []Recipe{{ID: "ice-wand", Name: "frozen wand", Aliases: []string{"ice wand"}}}
+	critters.Catalog = []CatalogCategory{{ID: "umbrellas", Items: []CatalogItem{{ID: "leaf-umbrela", Name: "Leaf umbrella", Aliases: []string{"Leaf umbrela"}}}}}
+	p := Profile{User: "someone"}
+	if err := store.SetDonated(p, "bugs", "Tarantula", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetDonated(p, "fossils", "Ankylo skul", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetDonated(p, "fishes", "coelacanth", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetDonated(p, "umbrellas", "Leaf umbrella", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetResident(p, "Sherbet", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetLearned(p, "frozen wand", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.renameKeys(critters.keyRenames()); err != nil {
+		t.Fatal(err)
+	}
+	if d := store.AllDonated(p); len(d["bugs"]) != 1 || d["bugs"][0] != "tarantula" || d["fishes"][0] != "coelacanth" || d["fossils"][0] != "ankylo-skul" {
+		t.Errorf("expected donations by name, old or new, to move to IDs, got %v", d)
+	}
+	if d := store.AllDonated(p); len(d["umbrellas"]) != 1 || d["umbrellas"][0] != "leaf-umbrela" {
+		t.Errorf("expected catalog items by name to move to IDs, got %v", d)
+	}
+	if r := store.Residents(p); len(r) != 1 || !r["sherbet"] {
+		t.Errorf("expected residents by name to move to IDs, got %v", r)
+	}
+	if l := store.Learned(p); len(l) != 1 || !l["ice-wand"] {
+		t.Errorf("expected learned recipes by name to move to IDs, got %v", l)
+	}
+	reloaded, err := loadStore(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := reloaded.AllDonated(p); d["bugs"][0] != "tarantula" {
+		t.Errorf("expected the move to be saved, got %v", d)
+	}
+
+	handler := donationsHandler(critters, store, nopLogger{})
+	post := func(form url.Values) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/donations", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(&http.Cookie{Name: userCookie, Value: "someone"})
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+	if code := post(url.Values{"category": {"bugs"}, "id": {"tarantula"}, "donated": {"false"}}); code != http.StatusNoContent {
+		t.Errorf("expected to take back the tarantula by ID, got %d", code)
+	}
+	if code := post(url.Values{"category": {"bugs"}, "name": {"Tarantula"}, "donated": {"true"}}); code != http.StatusNoContent {
+		t.Errorf("expected to donate the tarantula by name, got %d", code)
+	}
+	if code := post(url.Values{"category": {"bugs"}, "id": {"Tarantula"}, "donated": {"true"}}); code != http.StatusBadRequest {
+		t.Errorf("expected a name where the ID goes to be turned away, got %d", code)
+	}
+	if code := post(url.Values{"category": {"fossils"}, "name": {"Ankylo skull"}, "donated": {"false"}}); code != http.StatusNoContent {
+		t.Errorf("expected to take back the skull by its new name, got %d", code)
+	}
+	if d := store.AllDonated(p); len(d["bugs"]) != 1 || d["bugs"][0] != "tarantula" || len(d["fossils"]) != 0 {
+		t.Errorf("expected the tarantula donated by ID and the skull taken back, got %v", d)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+// Critter is a bug, fish or sea creature flattened down to the fields they
+// have in common, so they can be ranked against each other.
+type Critter struct {
+	Category    string            `json:"category"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Price       int               `json:"price"`
+	Months      []int             `json:"months"`
+	Hours       []int             `json:"hours"`
+	Location    string            `json:"location"`
+	ShadowSize  string            `json:"shadow_size,omitempty"`
+	Weather     string            `json:"weather,omitempty"`
+	Rarity      int               `json:"rarity,omitempty"`
+	Requirement string            `json:"requirement,omitempty"`
+	HardToCatch bool              `json:"hard_to_catch"`
+	Names       map[string]string `json:"names,omitempty"`
+	Aliases     []string          `json:"aliases,omitempty"`
+	HourMap     map[int]bool      `json:"-"`
+	Timing      Timing            `json:"timing"`
+}
+
+// seaCreatureLocation is where every sea creature lives.
+const seaCreatureLocation = "Sea (diving)"
+
+// critters returns every bug, fish and sea creature as a Critter.
+func (a ACNH) critters() []Critter {
+	var critters []Critter
+	for _, b := range a.Bugs {
+		critters = append(critters, Critter{
+			Category:    "bugs",
+			ID:          b.ID,
+			Name:        b.Name,
+			Price:       b.Price,
+			Months:      b.Months,
+			Hours:       b.Hours,
+			Location:    b.Location,
+			Weather:     b.Weather,
+			Rarity:      b.Rarity,
+			Requirement: b.Requirement,
+			HardToCatch: b.HardToCatch,
+			Names:       b.Names,
+			Aliases:     b.Aliases,
+			HourMap:     b.HourMap,
+			Timing:      b.Timing,
+		})
+	}
+	for _, f := range a.Fishes {
+		critters = append(critters, Critter{
+			Category:    "fishes",
+			ID:          f.ID,
+			Name:        f.Name,
+			Price:       f.Price,
+			Months:      f.Months,
+			Hours:       f.Hours,
+			Location:    f.Location,
+			ShadowSize:  f.ShadowSize,
+			Weather:     f.Weather,
+			Rarity:      f.Rarity,
+			Requirement: f.Requirement,
+			HardToCatch: f.HardToCatch,
+			Names:       f.Names,
+			Aliases:     f.Aliases,
+			HourMap:     f.HourMap,
+			Timing:      f.Timing,
+		})
+	}
+	for _, s := range a.SeaCreatures {
+		critters = append(critters, Critter{
+			Category:    "sea_creatures",
+			ID:          s.ID,
+			Name:        s.Name,
+			Price:       s.Price,
+			Months:      s.Months,
+			Hours:       s.Hours,
+			Location:    seaCreatureLocation,
+			ShadowSize:  s.ShadowSize,
+			Rarity:      s.Rarity,
+			Requirement: s.Requirement,
+			HardToCatch: s.HardToCatch,
+			Names:       s.Names,
+			Aliases:     s.Aliases,
+			HourMap:     s.HourMap,
+			Timing:      s.Timing,
+		})
+	}
+	return critters
+}
+
+// rarityWeights is roughly how likely a critter is to show up compared to a
+// common one, by rarity.  Critters without a rarity are treated as common.
+var rarityWeights = map[int]float64{
+	0: 1.0,
+	1: 1.0,
+	2: 0.6,
+	3: 0.35,
+	4: 0.15,
+	5: 0.05,
+}
+
+// RankingQuery is what to rank for.  Weather is "rain", "clear" or "" for
+// either; Location matches any location containing it.
+type RankingQuery struct {
+	Location string `json:"location,omitempty"`
+	Weather  string `json:"weather,omitempty"`
+	Weighted bool   `json:"weighted"`
+}
+
+func parseRankingQuery(q url.Values) RankingQuery {
+	query := RankingQuery{
+		Location: strings.TrimSpace(q.Get("location")),
+		Weather:  strings.ToLower(q.Get("weather")),
+	}
+	if query.Weather != "rain" && query.Weather != "clear" {
+		query.Weather = ""
+	}
+	query.Weighted, _ = strconv.ParseBool(q.Get("weighted"))
+	return query
+}
+
+func (q RankingQuery) matches(c Critter) bool {
+	if q.Location != "" && !strings.Contains(strings.ToLower(c.Location), strings.ToLower(q.Location)) {
+		return false
+	}
+	switch q.Weather {
+	case "rain":
+		return c.Weather != "No rain"
+	case "clear":
+		return c.Weather != "Rain"
+	}
+	return true
+}
+
+type RankedCritter struct {
+	Critter
+	Weight float64 `json:"weight"`
+	Value  float64 `json:"value"`
+}
+
+// LocationValue is what one catch at a location is worth on average, given
+// the chance of each critter there showing up.
+type LocationValue struct {
+	Location      string  `json:"location"`
+	Critters      int     `json:"critters"`
+	Best          string  `json:"best"`
+	ExpectedValue float64 `json:"expected_value"`
+}
+
+type Ranking struct {
+	Query     RankingQuery    `json:"query"`
+	Critters  []RankedCritter `json:"critters"`
+	Locations []LocationValue `json:"locations"`
+}
+
+// rankCritters ranks the critters that can be caught right now by what
+// they're worth, or by what they're worth times how likely they are to show
+// up if the query is weighted.  Each location's expected value is the
+// rarity-weighted average price of what's there.
+func rankCritters(critters ACNH, t time.Time, query RankingQuery) Ranking {
+	ranking := Ranking{Query: query, Critters: []RankedCritter{}, Locations: []LocationValue{}}
+	locations := make(map[string]*LocationValue)
+	totalWeights := make(map[string]float64)
+	var order []string
+
+	for _, c := range critters.critters() {
+		if !contains(c.Months, int(t.Month())-1) || !query.matches(c) {
+			continue
+		}
+		c.Timing = season.ForHour(c.HourMap, t.Hour())
+		if !c.Timing.AvailableNow {
+			continue
+		}
+
+		weight := rarityWeights[c.Rarity]
+		value := float64(c.Price)
+		if query.Weighted {
+			value *= weight
+		}
+		ranking.Critters = append(ranking.Critters, RankedCritter{c, weight, value})
+
+		lv, ok := locations[c.Location]
+		if !ok {
+			lv = &LocationValue{Location: c.Location}
+			locations[c.Location] = lv
+			order = append(order, c.Location)
+		}
+		lv.Critters++
+		lv.ExpectedValue += float64(c.Price) * weight
+		totalWeights[c.Location] += weight
+	}
+
+	sort.SliceStable(ranking.Critters, func(i, j int) bool {
+		return ranking.Critters[i].Value > ranking.Critters[j].Value
+	})
+	// Critters are sorted by value, so the first one seen at each location is
+	// the best one there.
+	for _, c := range ranking.Critters {
+		if lv := locations[c.Location]; lv.Best == "" {
+			lv.Best = c.Name
+		}
+	}
+
+	for _, location := range order {
+		lv := locations[location]
+		lv.ExpectedValue /= totalWeights[location]
+		ranking.Locations = append(ranking.Locations, *lv)
+	}
+	sort.SliceStable(ranking.Locations, func(i, j int) bool {
+		return ranking.Locations[i].ExpectedValue > ranking.Locations[j].ExpectedValue
+	})
+
+	return ranking
+}
+
+type RankingPage struct {
+	Ranking
+	Hour int
+}
+
+func rankingHandler(critters ACNH, languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		t, err := store.Now(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			logger.Log("failed loading timezone data", "error", err)
+			return
+		}
+
+		c := critters.forHemisphere(hemisphereFor(r, store, id))
+		ranking := rankCritters(c, t, parseRankingQuery(r.URL.Query()))
+		if err := render(w, r, languages, store, id, tmpl, http.StatusOK, RankingPage{ranking, t.Hour()}); err != nil {
+			logger.Log("failed rendering ranking", "error", err)
+		}
+	}
+}
+
+func rankingAPIHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		t, err := store.Now(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			logger.Log("failed loading timezone data", "error", err)
+			return
+		}
+
+		c := critters.forHemisphere(hemisphereFor(r, store, id))
+		writeJSON(w, rankCritters(c, t, parseRankingQuery(r.URL.Query())), logger)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRankCritters(t *testing.T) {
+	allYear := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	critters := ACNH{
+		Bugs: []Bug{
+			{Name: "common bug", Price: 100, Months: allYear, Location: "Flying around", Rarity: 1},
+			{Name: "rare bug", Price: 1000, Months: allYear, Location: "Flying around", Weather: "No rain", Rarity: 5},
+			{Name: "winter bug", Price: 5000, Months: []int{11, 0, 1}, Location: "Flying around", Rarity: 1},
+		},
+		Fishes: []Fish{
+			{Name: "rain fish", Price: 15000, Months: allYear, Location: "Sea (rainy day)", Weather: "Rain", Rarity: 5},
+			{Name: "morning fish", Price: 300, Months: allYear, Hours: []int{4, 5, 6, 7, 8}, Location: "River", Rarity: 2},
+		},
+	}
+	for i := range critters.Bugs {
+		critters.Bugs[i].Hours = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23}
+		setHourMap(&critters.Bugs[i])
+	}
+	critters.Fishes[0].Hours = critters.Bugs[0].Hours
+	for i := range critters.Fishes {
+		setHourMap(&critters.Fishes[i])
+	}
+	noon := time.Date(2020, time.July, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Name     string
+		Query    RankingQuery
+		Expected []string
+	}{
+		{"unweighted", RankingQuery{}, []string{"rain fish", "rare bug", "common bug"}},
+		{"weighted", RankingQuery{Weighted: true}, []string{"rain fish", "common bug", "rare bug"}},
+		{"clear", RankingQuery{Weather: "clear"}, []string{"rare bug", "common bug"}},
+		{"rain", RankingQuery{Weather: "rain"}, []string{"rain fish", "common bug"}},
+		{"location", RankingQuery{Location: "flying"}, []string{"rare bug", "common bug"}},
+	}
+
+	for _, test := range tests {
+		ranking := rankCritters(critters, noon, test.Query)
+		var got []string
+		for _, c := range ranking.Critters {
+			got = append(got, c.Name)
+		}
+		if len(got) != len(test.Expected) {
+			t.Errorf("Failed test '%s': expected %v, got %v", test.Name, test.Expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.Expected[i] {
+				t.Errorf("Failed test '%s': expected %v, got %v", test.Name, test.Expected, got)
+				break
+			}
+		}
+	}
+
+	ranking := rankCritters(critters, noon, RankingQuery{Weather: "clear"})
+	if len(ranking.Locations) != 1 {
+		t.Fatalf("expected one location, got %v", ranking.Locations)
+	}
+	// (100*1 + 1000*0.05) / 1.05
+	if ev := ranking.Locations[0].ExpectedValue; ev < 142.8 || ev > 142.9 {
+		t.Errorf("expected an expected value of about 142.86, got %f", ev)
+	}
+	if ranking.Locations[0].Best != "rare bug" {
+		t.Errorf("expected rare bug to be the best at %s, got %s", ranking.Locations[0].Location, ranking.Locations[0].Best)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+// hardToCatchRarity is the rarity at which a critter is worth making a
+// special trip for.
+const hardToCatchRarity = 4
+
+// rarityLabels are what each rarity is called on the page, by rarity.
+var rarityLabels = []string{"Unknown", "Common", "Uncommon", "Scarce", "Rare", "Very rare"}
+
+// hardToCatch is whether a critter is rare, or only shows up in the rain.
+func hardToCatch(rarity int, weather string) bool {
+	return rarity >= hardToCatchRarity || weather == "Rain"
+}
+
+func rarityLabel(rarity int) string {
+	if rarity < 0 || rarity >= len(rarityLabels) {
+		return rarityLabels[0]
+	}
+	return rarityLabels[rarity]
+}
+
+func (b Bug) RarityLabel() string {
+	return rarityLabel(b.Rarity)
+}
+
+func (f Fish) RarityLabel() string {
+	return rarityLabel(f.Rarity)
+}
+
+func (s SeaCreature) RarityLabel() string {
+	return rarityLabel(s.Rarity)
+}
+
+func (c Critter) RarityLabel() string {
+	return rarityLabel(c.Rarity)
+}
+
+// sortByRarity puts the rarest critters first, and the most valuable first
+// among those that are just as rare.
+func sortByRarity(a *ACNH) {
+	sort.SliceStable(a.Bugs, func(i, j int) bool {
+		return rarer(a.Bugs[i].Rarity, a.Bugs[i].Price, a.Bugs[j].Rarity, a.Bugs[j].Price)
+	})
+	sort.SliceStable(a.Fishes, func(i, j int) bool {
+		return rarer(a.Fishes[i].Rarity, a.Fishes[i].Price, a.Fishes[j].Rarity, a.Fishes[j].Price)
+	})
+	sort.SliceStable(a.SeaCreatures, func(i, j int) bool {
+		return rarer(a.SeaCreatures[i].Rarity, a.SeaCreatures[i].Price, a.SeaCreatures[j].Rarity, a.SeaCreatures[j].Price)
+	})
+}
+
+func rarer(rarityA, priceA, rarityB, priceB int) bool {
+	if rarityA != rarityB {
+		return rarityA > rarityB
+	}
+	return priceA > priceB
+}
+
+// rareLeavingSoon returns the hard to catch critters that haven't been
+// donated yet and won't be back next month, rarest first.
+func rareLeavingSoon(critters ACNH, donated map[string][]string, t time.Time) []Critter {
+	month := int(t.Month()) - 1
+	done := donatedSets(donated)
+
+	leaving := []Critter{}
+	for _, c := range critters.critters() {
+		if !c.HardToCatch || done[c.Category][c.ID] {
+			continue
+		}
+		if !contains(c.Months, month) || contains(c.Months, (month+1)%12) {
+			continue
+		}
+		c.Timing = season.ForHour(c.HourMap, t.Hour())
+		leaving = append(leaving, c)
+	}
+	sort.SliceStable(leaving, func(i, j int) bool {
+		return rarer(leaving[i].Rarity, leaving[i].Price, leaving[j].Rarity, leaving[j].Price)
+	})
+	return leaving
+}
+
+func leavingHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		t, err := store.Now(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			logger.Log("failed loading timezone data", "error", err)
+			return
+		}
+
+		c := critters.forHemisphere(hemisphereFor(r, store, id))
+		writeJSON(w, rareLeavingSoon(c, store.AllDonated(id), t), logger)
+	}
+}
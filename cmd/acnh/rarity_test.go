@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRareLeavingSoon(t *testing.T) {
+	critters := ACNH{
+		Bugs: []Bug{
+			{ID: "leaving-rare-bug", Name: "leaving rare bug", Price: 8000, Months: []int{5, 6}, Rarity: 4},
+			{ID: "staying-rare-bug", Name: "staying rare bug", Price: 8000, Months: []int{6, 7}, Rarity: 5},
+			{ID: "leaving-common-bug", Name: "leaving common bug", Price: 100, Months: []int{6}, Rarity: 1},
+			{ID: "donated-rare-bug", Name: "donated rare bug", Price: 12000, Months: []int{6}, Rarity: 5},
+		},
+		Fishes: []Fish{
+			{ID: "leaving-rain-fish", Name: "leaving rain fish", Price: 15000, Months: []int{6}, Weather: "Rain", Rarity: 3},
+			{ID: "leaving-rarer-fish", Name: "leaving rarer fish", Price: 10000, Months: []int{0, 6}, Rarity: 5},
+		},
+	}
+	for i := range critters.Bugs {
+		critters.Bugs[i].HardToCatch = hardToCatch(critters.Bugs[i].Rarity, critters.Bugs[i].Weather)
+	}
+	for i := range critters.Fishes {
+		critters.Fishes[i].HardToCatch = hardToCatch(critters.Fishes[i].Rarity, critters.Fishes[i].Weather)
+	}
+	donated := map[string][]string{"bugs": {"donated-bug", "donated-rare-bug"}}
+	july := time.Date(2020, time.July, 15, 12, 0, 0, 0, time.UTC)
+
+	leaving := rareLeavingSoon(critters, donated, july)
+
+	expected := []string{"leaving rarer fish", "leaving rare bug", "leaving rain fish"}
+	if len(leaving) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, leaving)
+	}
+	for i, c := range leaving {
+		if c.Name != expected[i] {
+			t.Errorf("expected %s at %d, got %s", expected[i], i, c.Name)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Recipe is a DIY recipe.  Season-limited recipes only have the months they
+// can be picked up in, same as critters; the rest have all twelve.
+type Recipe struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Materials []Ingredient `json:"materials"`
+	Source    string       `json:"source"`
+	Months    []int        `json:"months"`
+	// Aliases are names the recipe has gone by before.
+	Aliases []string `json:"aliases,omitempty"`
+	Learned bool     `json:"learned"`
+}
+
+func (r Recipe) Seasonal() bool {
+	return len(r.Months) < 12
+}
+
+func (r Recipe) Season() string {
+	return displayMonths(r.Months)
+}
+
+type Ingredient struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+// Material is a crafting material that can only be gathered in some months.
+// Anything that isn't one of these is around all year.
+type Material struct {
+	Name   string `json:"name"`
+	Months []int  `json:"months"`
+}
+
+// Plan is everything needed to craft a list of recipes.
+type Plan struct {
+	Recipes   []Recipe       `json:"recipes"`
+	Unknown   []string       `json:"unknown,omitempty"`
+	Materials []PlanMaterial `json:"materials"`
+	Month     int            `json:"month"`
+}
+
+type PlanMaterial struct {
+	Name         string `json:"name"`
+	Quantity     int    `json:"quantity"`
+	Seasonal     bool   `json:"seasonal"`
+	AvailableNow bool   `json:"available_now"`
+	Months       []int  `json:"months,omitempty"`
+}
+
+func (m PlanMaterial) Season() string {
+	return displayMonths(m.Months)
+}
+
+// displayMonths turns zero-based months into something like "Jun, Jul, Aug",
+// or "All year".
+func displayMonths(months []int) string {
+	return english.Months(months)
+}
+
+// Unavailable returns the seasonal materials that can't be gathered this
+// month.
+func (p Plan) Unavailable() []PlanMaterial {
+	var unavailable []PlanMaterial
+	for _, m := range p.Materials {
+		if !m.AvailableNow {
+			unavailable = append(unavailable, m)
+		}
+	}
+	return unavailable
+}
+
+// Unlearned returns the planned recipes the user doesn't know yet.
+func (p Plan) Unlearned() []Recipe {
+	var unlearned []Recipe
+	for _, r := range p.Recipes {
+		if !r.Learned {
+			unlearned = append(unlearned, r)
+		}
+	}
+	return unlearned
+}
+
+// planRecipes adds up the materials needed to craft every recipe in targets
+// (a recipe listed twice gets crafted twice) and flags the ones that are out
+// of season in the given zero-based month.  Targets are recipe IDs, though
+// names are still understood for links from before there were IDs; learned
+// is keyed by ID.  recipes and materials should already be in the island's
+// hemisphere.
+func planRecipes(recipes []Recipe, materials []Material, targets []string, learned map[string]bool, month int) Plan {
+	plan := Plan{Month: month}
+	byName := make(map[string]Recipe)
+	for _, r := range recipes {
+		byName[r.Name] = r
+	}
+	// IDs go in last, so that one can't be shadowed by another recipe's name.
+	for _, r := range recipes {
+		byName[r.ID] = r
+	}
+	seasons := make(map[string][]int)
+	for _, m := range materials {
+		seasons[m.Name] = m.Months
+	}
+
+	needed := make(map[string]int)
+	for _, target := range targets {
+		r, ok := byName[target]
+		if !ok {
+			plan.Unknown = append(plan.Unknown, target)
+			continue
+		}
+		r.Learned = learned[r.ID]
+		plan.Recipes = append(plan.Recipes, r)
+		for _, ingredient := range r.Materials {
+			needed[ingredient.Name] += ingredient.Quantity
+		}
+	}
+
+	for name, quantity := range needed {
+		m := PlanMaterial{
+			Name:         name,
+			Quantity:     quantity,
+			AvailableNow: true,
+		}
+		if months, ok := seasons[name]; ok {
+			m.Seasonal = true
+			m.Months = months
+			m.AvailableNow = contains(months, month)
+		}
+		plan.Materials = append(plan.Materials, m)
+	}
+	sort.Slice(plan.Materials, func(i, j int) bool {
+		return plan.Materials[i].Name < plan.Materials[j].Name
+	})
+
+	return plan
+}
+
+// Learned returns a copy of the set of IDs of recipes the user knows.
+func (s *Store) Learned(p Profile) map[string]bool {
+	var learned map[string]bool
+	s.view(p, func(i *Island) {
+		learned = copySet(i.Learned)
+	})
+	return learned
+}
+
+func (s *Store) SetLearned(p Profile, recipe string, learned bool) error {
+	return s.update(p, func(i *Island) {
+		if i.Learned == nil {
+			i.Learned = make(map[string]bool)
+		}
+		if learned {
+			i.Learned[recipe] = true
+		} else {
+			delete(i.Learned, recipe)
+		}
+	})
+}
+
+type RecipesPage struct {
+	Recipes []Recipe
+	Plan    *Plan
+}
+
+func recipesHandler(critters ACNH, languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		t, err := store.Now(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			logger.Log("failed loading timezone data", "error", err)
+			return
+		}
+
+		c := critters.forHemisphere(hemisphereFor(r, store, id))
+		learned := store.Learned(id)
+		page := RecipesPage{}
+		for _, recipe := range c.Recipes {
+			recipe.Learned = learned[recipe.ID]
+			page.Recipes = append(page.Recipes, recipe)
+		}
+		if targets := r.URL.Query()["recipe"]; len(targets) > 0 {
+			plan := planRecipes(c.Recipes, c.Materials, targets, learned, int(t.Month())-1)
+			page.Plan = &plan
+		}
+
+		if err := render(w, r, languages, store, id, tmpl, http.StatusOK, page); err != nil {
+			logger.Log("failed rendering recipes", "error", err)
+		}
+	}
+}
+
+// plannerHandler returns the plan for every recipe passed in as a "recipe"
+// parameter.
+func plannerHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		t, err := store.Now(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			logger.Log("failed loading timezone data", "error", err)
+			return
+		}
+		r.ParseForm()
+		c := critters.forHemisphere(hemisphereFor(r, store, id))
+		plan := planRecipes(c.Recipes, c.Materials, r.Form["recipe"], store.Learned(id), int(t.Month())-1)
+		writeJSON(w, plan, logger)
+	}
+}
+
+// learnedHandler returns the recipes the user knows on GET, and marks one
+// learned (or not) on POST.
+func learnedHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		switch r.Method {
+		case http.MethodGet:
+			learned := []string{}
+			for recipe := range store.Learned(id) {
+				learned = append(learned, recipe)
+			}
+			sort.Strings(learned)
+			writeJSON(w, learned, logger)
+		case http.MethodPost:
+			recipe := r.FormValue("id")
+			if recipe == "" {
+				recipe = critters.donationKey("recipes", r.FormValue("name"))
+			}
+			if !critters.hasEntry("recipes", recipe) {
+				http.Error(w, "unknown recipe", http.StatusBadRequest)
+				return
+			}
+			learned, err := strconv.ParseBool(r.FormValue("learned"))
+			if err != nil {
+				http.Error(w, "learned must be true or false", http.StatusBadRequest)
+				return
+			}
+			if err := store.SetLearned(id, recipe, learned); err != nil {
+				logger.Log("failed saving learned recipe", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanRecipes(t *testing.T) {
+	recipes := []Recipe{
+		{ID: "ladder", Name: "ladder", Materials: []Ingredient{{"wood", 4}, {"hardwood", 4}, {"softwood", 4}}, Months: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+		{ID: "cabin-wall", Name: "cabin wall", Materials: []Ingredient{{"wood", 6}}, Months: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+		{ID: "ice-wand", Name: "ice wand", Materials: []Ingredient{{"snowflake", 10}, {"large snowflake", 1}}, Months: []int{11, 0, 1}},
+	}
+	materials := []Material{
+		{"snowflake", []int{11, 0, 1}},
+		{"large snowflake", []int{11, 0, 1}},
+	}
+
+	plan := planRecipes(recipes, materials, []string{"ladder", "cabin-wall", "cabin wall", "ice-wand", "bogus"}, map[string]bool{"ladder": true}, 6)
+
+	if len(plan.Recipes) != 4 {
+		t.Errorf("expected 4 recipes in the plan, got %d", len(plan.Recipes))
+	}
+	if len(plan.Unknown) != 1 || plan.Unknown[0] != "bogus" {
+		t.Errorf("expected 'bogus' to be unknown, got %v", plan.Unknown)
+	}
+	expected := map[string]int{
+		"hardwood":        4,
+		"large snowflake": 1,
+		"snowflake":       10,
+		"softwood":        4,
+		"wood":            16,
+	}
+	if len(plan.Materials) != len(expected) {
+		t.Fatalf("expected %d materials, got %v", len(expected), plan.Materials)
+	}
+	for _, m := range plan.Materials {
+		if m.Quantity != expected[m.Name] {
+			t.Errorf("expected %d %s, got %d", expected[m.Name], m.Name, m.Quantity)
+		}
+	}
+	unavailable := plan.Unavailable()
+	if len(unavailable) != 2 {
+		t.Errorf("expected both snowflakes to be out of season in July, got %v", unavailable)
+	}
+	if len(plan.Unlearned()) != 3 {
+		t.Errorf("expected 3 unlearned recipes, got %v", plan.Unlearned())
+	}
+}
+
+func TestPlanRecipesSouthern(t *testing.T) {
+	critters := ACNH{
+		Recipes: []Recipe{
+			{Name: "ice wand", Materials: []Ingredient{{Name: "snowflake", Quantity: 10}}, Months: []int{11, 0, 1}},
+			{Name: "cherry-blossom clock", Materials: []Ingredient{{Name: "cherry-blossom petal", Quantity: 3}}, Months: []int{3}},
+		},
+		Materials: []Material{
+			{Name: "snowflake", Months: []int{11, 0, 1}},
+			{Name: "cherry-blossom petal", Months: []int{3}},
+		},
+	}.forHemisphere(Southern)
+
+	tests := []struct {
+		Name        string
+		Month       int
+		Unavailable []string
+	}{
+		{"southern January", 0, []string{"cherry-blossom petal", "snowflake"}},
+		{"southern July", 6, []string{"cherry-blossom petal"}},
+		{"southern October", 9, []string{"snowflake"}},
+	}
+	for _, test := range tests {
+		plan := planRecipes(critters.Recipes, critters.Materials, []string{"ice wand", "cherry-blossom clock"}, nil, test.Month)
+		var unavailable []string
+		for _, m := range plan.Unavailable() {
+			unavailable = append(unavailable, m.Name)
+		}
+		if !reflect.DeepEqual(unavailable, test.Unavailable) {
+			t.Errorf("failed test '%s': expected %v out of season, got %v", test.Name, test.Unavailable, unavailable)
+		}
+	}
+	if season := critters.Recipes[0].Season(); season != "Jun, Jul, Aug" {
+		t.Errorf("expected the ice wand in the southern winter, got %s", season)
+	}
+}
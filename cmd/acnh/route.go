@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+// Window is a stretch of play time in whole hours, from Start up to (but not
+// including) End.  It can run past midnight, so 19 to 2 is 7PM to 2AM.
+type Window struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Hours returns every hour in the window, in order.
+func (w Window) Hours() []int {
+	var hours []int
+	for h := w.Start; h != w.End; h = (h + 1) % 24 {
+		hours = append(hours, h)
+	}
+	return hours
+}
+
+func (w Window) DisplayStart() string {
+	return season.DisplayHour(w.Start)
+}
+
+func (w Window) DisplayEnd() string {
+	return season.DisplayHour(w.End)
+}
+
+// defaultWindowLength is how long a session is if only the start is given.
+const defaultWindowLength = 4
+
+// parseWindow reads "from" and "to" hours, defaulting to a few hours from
+// the current hour.
+func parseWindow(q url.Values, currentHour int) (Window, error) {
+	w := Window{Start: currentHour}
+	if from := q.Get("from"); from != "" {
+		h, err := strconv.Atoi(from)
+		if err != nil || h < 0 || h > 23 {
+			return w, errors.New("from must be an hour between 0 and 23")
+		}
+		w.Start = h
+	}
+	w.End = (w.Start + defaultWindowLength) % 24
+	if to := q.Get("to"); to != "" {
+		h, err := strconv.Atoi(to)
+		if err != nil || h < 0 || h > 23 {
+			return w, errors.New("to must be an hour between 0 and 23")
+		}
+		w.End = h
+	}
+	if w.Start == w.End {
+		return w, errors.New("from and to can't be the same hour")
+	}
+	return w, nil
+}
+
+// RouteCritter is a stretch of the window a critter can be caught in, so a
+// critter that leaves and comes back during the window shows up once for
+// each.  From is the first hour of the stretch and Until is when the critter
+// leaves; Arrives and Leaves say whether either happens during the window.
+type RouteCritter struct {
+	Critter
+	From    int  `json:"from"`
+	Until   int  `json:"until"`
+	Arrives bool `json:"arrives"`
+	Leaves  bool `json:"leaves"`
+
+	// Offsets into the window, for sorting.
+	from  int
+	until int
+}
+
+func (c RouteCritter) DisplayFrom() string {
+	return season.DisplayHour(c.From)
+}
+
+func (c RouteCritter) DisplayUntil() string {
+	return season.DisplayHour(c.Until)
+}
+
+// RouteStop is everything worth catching at one location, in the order it
+// leaves.
+type RouteStop struct {
+	Location string         `json:"location"`
+	Critters []RouteCritter `json:"critters"`
+}
+
+type Route struct {
+	Window Window      `json:"window"`
+	Stops  []RouteStop `json:"stops"`
+}
+
+// planRoute works out which critters that haven't been donated yet can be
+// caught during the window, groups them by location and orders the
+// locations so whatever leaves first gets caught first.  Critters have to be
+// around in t's month; the window starts on t's day.
+func planRoute(critters ACNH, donated map[string][]string, t time.Time, window Window) Route {
+	route := Route{Window: window, Stops: []RouteStop{}}
+	hours := window.Hours()
+	month := int(t.Month()) - 1
+
+	done := donatedSets(donated)
+
+	stops := make(map[string]*RouteStop)
+	var locations []string
+	for _, c := range critters.critters() {
+		if done[c.Category][c.ID] || !contains(c.Months, month) {
+			continue
+		}
+		runs := windowRuns(func(i int) bool { return c.HourMap[hours[i]] }, len(hours))
+		if len(runs) == 0 {
+			continue
+		}
+		stop, ok := stops[c.Location]
+		if !ok {
+			stop = &RouteStop{Location: c.Location}
+			stops[c.Location] = stop
+			locations = append(locations, c.Location)
+		}
+		for _, run := range runs {
+			first, last := run.Start, run.Start+run.Length-1
+			rc := RouteCritter{
+				Critter: c,
+				From:    hours[first],
+				Until:   (hours[last] + 1) % 24,
+				Arrives: first > 0,
+				Leaves:  last < len(hours)-1,
+				from:    first,
+				until:   last + 1,
+			}
+			rc.Timing = season.ForHour(c.HourMap, hours[first])
+			stop.Critters = append(stop.Critters, rc)
+		}
+	}
+
+	for _, location := range locations {
+		stop := stops[location]
+		sort.SliceStable(stop.Critters, func(i, j int) bool {
+			a, b := stop.Critters[i], stop.Critters[j]
+			if a.until != b.until {
+				return a.until < b.until
+			}
+			return a.from < b.from
+		})
+		route.Stops = append(route.Stops, *stop)
+	}
+	// Go wherever something leaves soonest first.  When that's a tie, go
+	// wherever something's already out, then wherever there's the most to
+	// catch.
+	sort.SliceStable(route.Stops, func(i, j int) bool {
+		a, b := route.Stops[i], route.Stops[j]
+		if a.Critters[0].until != b.Critters[0].until {
+			return a.Critters[0].until < b.Critters[0].until
+		}
+		if a.Critters[0].from != b.Critters[0].from {
+			return a.Critters[0].from < b.Critters[0].from
+		}
+		return len(a.Critters) > len(b.Critters)
+	})
+
+	return route
+}
+
+// windowRuns finds the runs of hours 0 to n-1 into the window that are in
+// the set.  The window doesn't wrap the way the day does, so a run
+// season.Spans wraps around from the end to the start is two runs here.
+func windowRuns(in func(int) bool, n int) []season.Span {
+	var runs []season.Span
+	for _, s := range season.Spans(in, n) {
+		if end := s.Start + s.Length; end > n {
+			runs = append(runs, season.Span{Start: 0, Length: end - n})
+			s.Length = n - s.Start
+		}
+		runs = append(runs, s)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Start < runs[j].Start })
+	return runs
+}
+
+type RoutePage struct {
+	Route
+	Hours []int
+	Error string
+}
+
+func routeHandler(critters ACNH, languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		t, err := store.Now(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			logger.Log("failed loading timezone data", "error", err)
+			return
+		}
+
+		page := RoutePage{}
+		status := http.StatusOK
+		for h := 0; h < 24; h++ {
+			page.Hours = append(page.Hours, h)
+		}
+		window, err := parseWindow(r.URL.Query(), t.Hour())
+		if err != nil {
+			status = http.StatusBadRequest
+			page.Error = err.Error()
+		} else {
+			c := critters.forHemisphere(hemisphereFor(r, store, id))
+			page.Route = planRoute(c, store.AllDonated(id), t, window)
+		}
+		page.Window = window
+		if err := render(w, r, languages, store, id, tmpl, status, page); err != nil {
+			logger.Log("failed rendering route", "error", err)
+		}
+	}
+}
+
+func routeAPIHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		t, err := store.Now(id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			logger.Log("failed loading timezone data", "error", err)
+			return
+		}
+
+		window, err := parseWindow(r.URL.Query(), t.Hour())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c := critters.forHemisphere(hemisphereFor(r, store, id))
+		writeJSON(w, planRoute(c, store.AllDonated(id), t, window), logger)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlanRoute(t *testing.T) {
+	allYear := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	critters := ACNH{
+		Bugs: []Bug{
+			{ID: "evening-bug", Name: "evening bug", Months: allYear, Hours: []int{17, 18, 19, 20}, Location: "Flying around"},
+			{ID: "night-bug", Name: "night bug", Months: allYear, Hours: []int{23, 0, 1, 2, 3}, Location: "On trees"},
+			{ID: "donated-bug", Name: "donated bug", Months: allYear, Hours: []int{19, 20}, Location: "On trees"},
+			{ID: "day-bug", Name: "day bug", Months: allYear, Hours: []int{8, 9, 10}, Location: "Flying around"},
+		},
+		Fishes: []Fish{
+			{ID: "late-fish", Name: "late fish", Months: allYear, Hours: []int{16, 17, 18, 19, 20, 21, 22, 23, 0, 1, 2, 3, 4}, Location: "River"},
+			{ID: "winter-fish", Name: "winter fish", Months: []int{11, 0, 1}, Hours: []int{19, 20}, Location: "River"},
+		},
+	}
+	for i := range critters.Bugs {
+		setHourMap(&critters.Bugs[i])
+	}
+	for i := range critters.Fishes {
+		setHourMap(&critters.Fishes[i])
+	}
+	donated := map[string][]string{"bugs": {"donated-bug"}}
+	july := time.Date(2020, time.July, 1, 12, 0, 0, 0, time.UTC)
+
+	route := planRoute(critters, donated, july, Window{19, 1})
+
+	expected := []string{"Flying around", "River", "On trees"}
+	if len(route.Stops) != len(expected) {
+		t.Fatalf("expected stops %v, got %v", expected, route.Stops)
+	}
+	for i, stop := range route.Stops {
+		if stop.Location != expected[i] {
+			t.Errorf("expected stop %d to be %s, got %s", i, expected[i], stop.Location)
+		}
+		if len(stop.Critters) != 1 {
+			t.Errorf("expected one critter at %s, got %v", stop.Location, stop.Critters)
+		}
+	}
+
+	evening := route.Stops[0].Critters[0]
+	if evening.Arrives || !evening.Leaves || evening.Until != 21 {
+		t.Errorf("expected evening bug to already be out and leave at 21, got %+v", evening)
+	}
+	night := route.Stops[2].Critters[0]
+	if !night.Arrives || night.Leaves || night.From != 23 {
+		t.Errorf("expected night bug to show up at 23 and stay, got %+v", night)
+	}
+}
+
+func TestPlanRouteSplitHours(t *testing.T) {
+	allYear := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	critters := ACNH{
+		Bugs: []Bug{
+			{ID: "split-bug", Name: "split bug", Months: allYear, Hours: []int{4, 5, 6, 7, 17, 18}, Location: "On flowers"},
+		},
+	}
+	setHourMap(&critters.Bugs[0])
+	july := time.Date(2020, time.July, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		Name     string
+		Window   Window
+		Expected [][2]int
+	}{
+		{"morning and evening", Window{3, 23}, [][2]int{{4, 8}, {17, 19}}},
+		{"either end of the window", Window{18, 5}, [][2]int{{18, 19}, {4, 5}}},
+		{"one stretch", Window{5, 12}, [][2]int{{5, 8}}},
+	}
+
+	for _, test := range tests {
+		route := planRoute(critters, nil, july, test.Window)
+		if len(route.Stops) != 1 {
+			t.Errorf("failed test '%s': expected one stop, got %v", test.Name, route.Stops)
+			continue
+		}
+		var got [][2]int
+		for _, c := range route.Stops[0].Critters {
+			got = append(got, [2]int{c.From, c.Until})
+		}
+		if !reflect.DeepEqual(got, test.Expected) {
+			t.Errorf("failed test '%s': expected %v, got %v", test.Name, test.Expected, got)
+		}
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		Query       string
+		Expected    Window
+		ShouldError bool
+	}{
+		{"", Window{20, 0}, false},
+		{"from=19&to=23", Window{19, 23}, false},
+		{"from=22&to=2", Window{22, 2}, false},
+		{"from=8", Window{8, 12}, false},
+		{"from=25", Window{}, true},
+		{"from=5&to=5", Window{}, true},
+	}
+
+	for _, test := range tests {
+		q, _ := url.ParseQuery(test.Query)
+		got, err := parseWindow(q, 20)
+		if test.ShouldError {
+			if err == nil {
+				t.Errorf("Failed test '%s', should have errored but didn't", test.Query)
+			}
+			continue
+		}
+		if err != nil || got != test.Expected {
+			t.Errorf("Failed test '%s': expected %v, got %v (err %v)", test.Query, test.Expected, got, err)
+		}
+	}
+}
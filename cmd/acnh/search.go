@@ -0,0 +1,298 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// searchCategories are the categories search looks through, in the order
+// they're shown.
+var searchCategories = []SearchCategory{
+	{"bugs", "Bugs"},
+	{"fishes", "Fish"},
+	{"sea_creatures", "Sea creatures"},
+	{"umbrellas", "Umbrellas"},
+}
+
+type SearchCategory struct {
+	ID   string
+	Name string
+}
+
+// Search is everything that can be searched on.  Empty fields (and zero
+// prices) match everything.
+type Search struct {
+	Query      string `json:"q,omitempty"`
+	Category   string `json:"category,omitempty"`
+	Location   string `json:"location,omitempty"`
+	ShadowSize string `json:"shadow,omitempty"`
+	MinPrice   int    `json:"min_price,omitempty"`
+	MaxPrice   int    `json:"max_price,omitempty"`
+	// Donated is "yes", "no", or empty for both.
+	Donated string `json:"donated,omitempty"`
+}
+
+// Values is the search as query parameters, so it can be linked to.
+func (s Search) Values() url.Values {
+	v := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	set("q", s.Query)
+	set("category", s.Category)
+	set("location", s.Location)
+	set("shadow", s.ShadowSize)
+	if s.MinPrice > 0 {
+		v.Set("min_price", strconv.Itoa(s.MinPrice))
+	}
+	if s.MaxPrice > 0 {
+		v.Set("max_price", strconv.Itoa(s.MaxPrice))
+	}
+	set("donated", s.Donated)
+	return v
+}
+
+func (s Search) Encode() string {
+	return s.Values().Encode()
+}
+
+// QueryString is Encode for going after the ? in a link.  It's already escaped,
+// so the template shouldn't escape it again.
+func (s Search) QueryString() template.URL {
+	return template.URL(s.Encode())
+}
+
+func parseSearch(q url.Values) (Search, error) {
+	s := Search{
+		Query:      strings.TrimSpace(q.Get("q")),
+		Category:   q.Get("category"),
+		Location:   strings.TrimSpace(q.Get("location")),
+		ShadowSize: q.Get("shadow"),
+		Donated:    q.Get("donated"),
+	}
+	for _, p := range []struct {
+		key   string
+		price *int
+	}{{"min_price", &s.MinPrice}, {"max_price", &s.MaxPrice}} {
+		if v := q.Get(p.key); v != "" {
+			price, err := strconv.Atoi(v)
+			if err != nil || price < 0 {
+				return s, errors.New(p.key + " must be a number of bells")
+			}
+			*p.price = price
+		}
+	}
+	return s, nil
+}
+
+// SearchResult is one thing that matched.  Price is what it sells for, or 0
+// if it can't be sold.  Match is how the name matched: "exact", "substring",
+// "fuzzy", or "" if there was no query.
+type SearchResult struct {
+	Category   string `json:"category"`
+	Name       string `json:"name"`
+	Price      int    `json:"price"`
+	Location   string `json:"location,omitempty"`
+	ShadowSize string `json:"shadow_size,omitempty"`
+	Donated    bool   `json:"donated"`
+	Match      string `json:"match,omitempty"`
+
+	score int
+	// key is the ID it's donated under.
+	key string
+}
+
+// searchAll searches bugs, fish, sea creatures and umbrellas, best matches
+// first.  donated is what Store.AllDonated returns for the user.
+func searchAll(critters ACNH, s Search, donated map[string][]string) []SearchResult {
+	done := donatedSets(donated)
+	var candidates []SearchResult
+	for _, c := range critters.critters() {
+		candidates = append(candidates, SearchResult{
+			Category:   c.Category,
+			Name:       c.Name,
+			Price:      c.Price,
+			Location:   c.Location,
+			ShadowSize: c.ShadowSize,
+			key:        c.ID,
+		})
+	}
+	for _, category := range critters.Catalog {
+		if category.ID != "umbrellas" {
+			continue
+		}
+		for _, item := range category.Items {
+			candidates = append(candidates, SearchResult{
+				Category: category.ID,
+				Name:     item.Name,
+				Price:    item.SellPrice.Amount,
+				Location: item.Source,
+				key:      item.ID,
+			})
+		}
+	}
+
+	query := strings.ToLower(s.Query)
+	results := []SearchResult{}
+	for _, r := range candidates {
+		r.Donated = done[r.Category][r.key]
+		if s.Category != "" && s.Category != r.Category {
+			continue
+		}
+		if s.Location != "" && !strings.Contains(strings.ToLower(r.Location), strings.ToLower(s.Location)) {
+			continue
+		}
+		if s.ShadowSize != "" && !strings.EqualFold(s.ShadowSize, r.ShadowSize) {
+			continue
+		}
+		if (s.MinPrice > 0 && r.Price < s.MinPrice) || (s.MaxPrice > 0 && r.Price > s.MaxPrice) {
+			continue
+		}
+		if (s.Donated == "yes" && !r.Donated) || (s.Donated == "no" && r.Donated) {
+			continue
+		}
+		if query != "" {
+			score, ok := fuzzyMatch(query, strings.ToLower(r.Name))
+			if !ok {
+				continue
+			}
+			r.score = score
+			switch {
+			case score == 0:
+				r.Match = "exact"
+			case score == 1:
+				r.Match = "substring"
+			default:
+				r.Match = "fuzzy"
+			}
+		}
+		results = append(results, r)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score < results[j].score
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// fuzzyMatch scores how well query matches name, lower being better: 0 for
+// the whole name, 1 for part of it, 2 for its letters appearing in order
+// ("tarntla"), and 3 and up for a word that's a typo or two away
+// ("coelecanth").  Both should already be lower case.
+func fuzzyMatch(query, name string) (int, bool) {
+	if query == name {
+		return 0, true
+	}
+	if strings.Contains(name, query) {
+		return 1, true
+	}
+	if len(query) < 3 {
+		return 0, false
+	}
+	if isSubsequence(query, name) {
+		return 2, true
+	}
+	allowed := 1
+	if len(query) > 6 {
+		allowed = 2
+	}
+	best := -1
+	for _, word := range append(strings.Fields(name), name) {
+		if d := editDistance(query, word); best < 0 || d < best {
+			best = d
+		}
+	}
+	if best <= allowed {
+		return 2 + best, true
+	}
+	return 0, false
+}
+
+func isSubsequence(s, t string) bool {
+	i := 0
+	for j := 0; i < len(s) && j < len(t); j++ {
+		if s[i] == t[j] {
+			i++
+		}
+	}
+	return i == len(s)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+type SearchPage struct {
+	Search      Search
+	Results     []SearchResult
+	Categories  []SearchCategory
+	ShadowSizes []string
+	Error       string
+}
+
+func searchHandler(critters ACNH, languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		page := SearchPage{Categories: searchCategories, ShadowSizes: shadowSizes}
+		status := http.StatusOK
+		search, err := parseSearch(r.URL.Query())
+		page.Search = search
+		if err != nil {
+			status = http.StatusBadRequest
+			page.Error = err.Error()
+		} else {
+			page.Results = searchAll(critters, search, store.AllDonated(id))
+		}
+		if err := render(w, r, languages, store, id, tmpl, status, page); err != nil {
+			logger.Log("failed rendering search", "error", err)
+		}
+	}
+}
+
+func searchAPIHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		search, err := parseSearch(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, searchAll(critters, search, store.AllDonated(store.profile(w, r))), logger)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		Query    string
+		Name     string
+		Expected int
+		Matches  bool
+	}{
+		{"koi", "koi", 0, true},
+		{"koi", "ranchu goldfish", 0, false},
+		{"gold", "ranchu goldfish", 1, true},
+		{"trntla", "tarantula", 2, true},
+		{"coelecanth", "coelacanth", 3, true},
+		{"bss", "sea bass", 2, true},
+		{"xy", "oxygen", 1, true},
+		{"xz", "oxygen", 0, false},
+		{"shark", "saw shark", 1, true},
+		{"shrak", "saw shark", 0, false},
+		{"sherk", "saw shark", 3, true},
+		{"umbrela", "leaf umbrella", 2, true},
+		{"zzzz", "tuna", 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := fuzzyMatch(test.Query, test.Name)
+		if ok != test.Matches || (ok && got != test.Expected) {
+			t.Errorf("Failed test '%s' against '%s': expected %d (%v), got %d (%v)", test.Query, test.Name, test.Expected, test.Matches, got, ok)
+		}
+	}
+}
+
+func TestSearchAll(t *testing.T) {
+	critters := ACNH{
+		Bugs: []Bug{
+			{ID: "tarantula", Name: "Tarantula", Price: 8000, Location: "Flying around"},
+			{ID: "ant", Name: "Ant", Price: 80, Location: "On rotten food"},
+		},
+		Fishes: []Fish{
+			{ID: "sea-bass", Name: "Sea bass", Price: 400, Location: "Sea", ShadowSize: "XL"},
+			{ID: "coelacanth", Name: "Coelacanth", Price: 15000, Location: "Sea (rainy day)", ShadowSize: "XXL"},
+		},
+		Catalog: []CatalogCategory{
+			{ID: "umbrellas", Items: []CatalogItem{{Name: "Sea umbrella", SellPrice: Price{Amount: 200}, Source: "Nook's Cranny"}}},
+			{ID: "rugs", Items: []CatalogItem{{Name: "Sea rug", SellPrice: Price{Amount: 200}}}},
+		},
+	}
+	donated := map[string][]string{"fishes": {"sea-bass"}}
+
+	tests := []struct {
+		Query    string
+		Expected []string
+	}{
+		{"q=sea", []string{"Sea bass", "Sea umbrella"}},
+		{"location=sea", []string{"Coelacanth", "Sea bass"}},
+		{"location=sea&donated=no", []string{"Coelacanth"}},
+		{"min_price=300&max_price=9000", []string{"Sea bass", "Tarantula"}},
+		{"shadow=XXL", []string{"Coelacanth"}},
+		{"shadow=xl", []string{"Sea bass"}},
+		{"q=coelecanth", []string{"Coelacanth"}},
+		{"category=bugs", []string{"Ant", "Tarantula"}},
+	}
+
+	for _, test := range tests {
+		q, _ := url.ParseQuery(test.Query)
+		search, err := parseSearch(q)
+		if err != nil {
+			t.Errorf("Failed test '%s': %v", test.Query, err)
+			continue
+		}
+		results := searchAll(critters, search, donated)
+		var got []string
+		for _, r := range results {
+			got = append(got, r.Name)
+		}
+		if len(got) != len(test.Expected) {
+			t.Errorf("Failed test '%s': expected %v, got %v", test.Query, test.Expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.Expected[i] {
+				t.Errorf("Failed test '%s': expected %v, got %v", test.Query, test.Expected, got)
+				break
+			}
+		}
+		if search.Encode() != q.Encode() {
+			t.Errorf("Failed test '%s': search encoded back as '%s'", test.Query, search.Encode())
+		}
+	}
+
+	if _, err := parseSearch(url.Values{"min_price": {"lots"}}); err == nil {
+		t.Errorf("expected a bad min_price to error")
+	}
+}
+
+// The search page echoes the query back, so it had better be escaped.
+func TestSearchHandlerEscapes(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	tmpl, err := loadTemplate("search", nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("GET", "/search?q="+url.QueryEscape(`"><script>alert(1)</script>`), nil)
+	w := httptest.NewRecorder()
+	searchHandler(slashCritters(), Languages{"en": english}, store, tmpl, nopLogger{})(w, r)
+	if body := w.Body.String(); strings.Contains(body, "<script>alert") || !strings.Contains(body, "&lt;script&gt;alert") {
+		t.Errorf("expected the query to be escaped, got %s", body)
+	}
+}
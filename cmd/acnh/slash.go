@@ -0,0 +1,355 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+// slashMaxSkew is how far a signed request's timestamp can be from now
+// before it's treated as a replay.
+const slashMaxSkew = 5 * time.Minute
+
+var errBadSignature = errors.New("bad request signature")
+
+// SlashVerifier checks that a command really came from the platform.
+type SlashVerifier interface {
+	Verify(r *http.Request, body []byte, now time.Time) error
+}
+
+// SlashPlatform is how one chat platform signs commands and wants its
+// replies.
+type SlashPlatform struct {
+	Verifier SlashVerifier
+	Reply    func(w http.ResponseWriter, r *http.Request, reply SlashReply, logger Logger)
+}
+
+// slackVerifier checks Slack's request signatures.
+type slackVerifier struct {
+	secret string
+}
+
+func (v slackVerifier) Verify(r *http.Request, body []byte, now time.Time) error {
+	ts := r.Header.Get("X-Slack-Request-Timestamp")
+	if err := checkTimestamp(ts, now); err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, []byte(v.secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(r.Header.Get("X-Slack-Signature"))) {
+		return errBadSignature
+	}
+	return nil
+}
+
+// hmacVerifier checks the signatures our own bots send: the same as
+// webhooks, over the timestamp, a colon and the body.
+type hmacVerifier struct {
+	secret string
+}
+
+func (v hmacVerifier) Verify(r *http.Request, body []byte, now time.Time) error {
+	ts := r.Header.Get("X-ACNH-Timestamp")
+	if err := checkTimestamp(ts, now); err != nil {
+		return err
+	}
+	want := sign(v.secret, append([]byte(ts+":"), body...))
+	if !hmac.Equal([]byte(want), []byte(r.Header.Get(signatureHeader))) {
+		return errBadSignature
+	}
+	return nil
+}
+
+// checkTimestamp makes sure a Unix timestamp is recent.
+func checkTimestamp(ts string, now time.Time) error {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errBadSignature
+	}
+	skew := now.Sub(time.Unix(sec, 0))
+	if skew > slashMaxSkew || skew < -slashMaxSkew {
+		return errBadSignature
+	}
+	return nil
+}
+
+// slashPlatforms returns the platforms that have secrets configured in the
+// environment.
+func slashPlatforms() map[string]SlashPlatform {
+	platforms := make(map[string]SlashPlatform)
+	if secret := os.Getenv("ACNH_SLACK_SIGNING_SECRET"); secret != "" {
+		platforms["slack"] = SlashPlatform{Verifier: slackVerifier{secret}, Reply: slackReply}
+	}
+	if secret := os.Getenv("ACNH_SLASH_SECRET"); secret != "" {
+		platforms["generic"] = SlashPlatform{Verifier: hmacVerifier{secret}, Reply: genericReply}
+	}
+	return platforms
+}
+
+// SlashReply is the answer to a command: a title and a line per thing.
+type SlashReply struct {
+	Title string   `json:"title"`
+	Lines []string `json:"lines"`
+}
+
+func (r SlashReply) Text() string {
+	var b strings.Builder
+	b.WriteString(r.Title)
+	for _, l := range r.Lines {
+		b.WriteString("\n• " + l)
+	}
+	return b.String()
+}
+
+// slackReply answers in the channel with a block for the title and one for
+// the lines.
+func slackReply(w http.ResponseWriter, r *http.Request, reply SlashReply, logger Logger) {
+	type text struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	type block struct {
+		Type string `json:"type"`
+		Text text   `json:"text"`
+	}
+	blocks := []block{{Type: "section", Text: text{"mrkdwn", "*" + reply.Title + "*"}}}
+	if len(reply.Lines) > 0 {
+		blocks = append(blocks, block{Type: "section", Text: text{"mrkdwn", "• " + strings.Join(reply.Lines, "\n• ")}})
+	}
+	writeJSON(w, struct {
+		ResponseType string  `json:"response_type"`
+		Text         string  `json:"text"`
+		Blocks       []block `json:"blocks"`
+	}{"in_channel", reply.Text(), blocks}, logger)
+}
+
+// genericReply is JSON, or plain text for clients that ask for it.
+func genericReply(w http.ResponseWriter, r *http.Request, reply SlashReply, logger Logger) {
+	if strings.Contains(r.Header.Get("Accept"), "text/plain") {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, reply.Text())
+		return
+	}
+	writeJSON(w, struct {
+		Text string `json:"text"`
+		SlashReply
+	}{reply.Text(), reply}, logger)
+}
+
+// slashCategories are what "now" can be narrowed down to, and what each
+// is called.
+var slashCategories = map[string]string{
+	"bugs": "bugs", "bug": "bugs",
+	"fish": "fishes", "fishes": "fishes",
+	"sea": "sea_creatures", "sea_creatures": "sea_creatures", "diving": "sea_creatures",
+}
+
+const slashHelp = "Try `now [bugs|fish|sea] [north|south]`, `when <critter> [north|south]` or `price <critter or item>`."
+
+// runSlashCommand answers a command like "now fish" at t.  Hemisphere can
+// be given anywhere as "north" or "south", and the command itself ("/acnh")
+// can be left on the front.
+func runSlashCommand(critters ACNH, text string, t time.Time) SlashReply {
+	h := Northern
+	var words []string
+	for i, word := range strings.Fields(strings.ToLower(text)) {
+		if i == 0 && strings.HasPrefix(word, "/") {
+			continue
+		}
+		if hemisphere, err := season.ParseHemisphere(word); err == nil {
+			h = hemisphere
+			continue
+		}
+		words = append(words, word)
+	}
+	if len(words) == 0 {
+		return SlashReply{Title: slashHelp}
+	}
+	critters = critters.forHemisphere(h)
+	args := strings.Join(words[1:], " ")
+	switch words[0] {
+	case "now":
+		return slashNow(critters, args, h, t)
+	case "when":
+		return slashWhen(critters, args, h, t)
+	case "price":
+		return slashPrice(critters, args)
+	}
+	return SlashReply{Title: "I don't know how to " + words[0] + ". " + slashHelp}
+}
+
+func slashNow(critters ACNH, category string, h Hemisphere, t time.Time) SlashReply {
+	want := ""
+	if category != "" {
+		var ok bool
+		if want, ok = slashCategories[category]; !ok {
+			return SlashReply{Title: "There's no " + category + ". " + slashHelp}
+		}
+	}
+	reply := SlashReply{Title: fmt.Sprintf("Out now at %s in the %s hemisphere", season.DisplayHour(t.Hour()), h.Name())}
+	for _, c := range critters.critters() {
+		if want != "" && c.Category != want {
+			continue
+		}
+		at := season.At(c.Months, c.HourMap, t)
+		if at.Status != StatusNow {
+			continue
+		}
+		until := "all day"
+		if !at.AvailableAllDay {
+			until = "until " + at.DisplayUntil()
+		}
+		reply.Lines = append(reply.Lines, fmt.Sprintf("%s: %d bells, %s, %s", c.Name, c.Price, c.Location, until))
+	}
+	if len(reply.Lines) == 0 {
+		reply.Title = "Nothing's out right now."
+	}
+	return reply
+}
+
+func slashWhen(critters ACNH, name string, h Hemisphere, t time.Time) SlashReply {
+	c, ok := findCritter(critters, name)
+	if !ok {
+		return SlashReply{Title: "I couldn't find a critter called " + name + "."}
+	}
+	at := season.At(c.Months, c.HourMap, t)
+	reply := SlashReply{
+		Title: fmt.Sprintf("%s (%s hemisphere)", c.Name, h.Name()),
+		Lines: []string{
+			"Months: " + season.DescribeMonths(c.Months),
+			"Hours: " + season.DescribeHours(c.HourMap),
+			"Where: " + c.Location,
+		},
+	}
+	switch at.Status {
+	case StatusNow:
+		reply.Lines = append(reply.Lines, "It's out right now.")
+	case StatusLaterToday, StatusThisMonth:
+		reply.Lines = append(reply.Lines, "It's in season; next out at "+at.DisplayAt()+".")
+	default:
+		reply.Lines = append(reply.Lines, "Out of season until "+at.DisplayNextMonth()+".")
+	}
+	if c.Requirement != "" {
+		reply.Lines = append(reply.Lines, "Needs: "+c.Requirement)
+	}
+	return reply
+}
+
+func slashPrice(critters ACNH, name string) SlashReply {
+	query := strings.ToLower(strings.TrimSpace(name))
+	if query == "" {
+		return SlashReply{Title: "Price of what? " + slashHelp}
+	}
+	type match struct {
+		line  string
+		score int
+	}
+	var best []match
+	consider := func(itemName, line string) {
+		score, ok := fuzzyMatch(query, strings.ToLower(itemName))
+		if !ok {
+			return
+		}
+		if len(best) > 0 && score > best[0].score {
+			return
+		}
+		if len(best) > 0 && score < best[0].score {
+			best = nil
+		}
+		best = append(best, match{line, score})
+	}
+	for _, c := range critters.critters() {
+		consider(c.Name, fmt.Sprintf("%s sells for %d bells", c.Name, c.Price))
+	}
+	for _, category := range critters.Catalog {
+		for _, item := range category.Items {
+			if item.SellPrice.State != Priced {
+				continue
+			}
+			consider(item.Name, fmt.Sprintf("%s (%s) sells for %d bells", item.Name, category.Name, item.SellPrice.Amount))
+		}
+	}
+	if len(best) == 0 {
+		return SlashReply{Title: "I couldn't find anything called " + name + "."}
+	}
+	reply := SlashReply{Title: "Prices for " + name}
+	for i, m := range best {
+		if i == 10 {
+			break
+		}
+		reply.Lines = append(reply.Lines, m.line)
+	}
+	return reply
+}
+
+// findCritter finds the critter whose name best matches name.
+func findCritter(critters ACNH, name string) (Critter, bool) {
+	query := strings.ToLower(strings.TrimSpace(name))
+	var found Critter
+	best := -1
+	if query == "" {
+		return found, false
+	}
+	for _, c := range critters.critters() {
+		if score, ok := fuzzyMatch(query, strings.ToLower(c.Name)); ok && (best < 0 || score < best) {
+			found, best = c, score
+		}
+	}
+	return found, best >= 0
+}
+
+// slashHandler takes commands posted by chat platforms to
+// /api/slash/{platform}, form-encoded with the command's arguments in
+// "text" and optionally a time zone in "tz", and answers in whatever form
+// the platform wants.  Platforms without a secret configured don't exist.
+func slashHandler(critters ACNH, platforms map[string]SlashPlatform, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		platform, ok := platforms[strings.TrimPrefix(r.URL.Path, "/api/slash/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<16))
+		if err != nil {
+			http.Error(w, "couldn't read request", http.StatusBadRequest)
+			return
+		}
+		if err := platform.Verifier.Verify(r, body, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, "commands must be form-encoded", http.StatusBadRequest)
+			return
+		}
+		tz := form.Get("tz")
+		if tz == "" {
+			tz = defaultTimezone
+		}
+		t, err := now(tz)
+		if err != nil {
+			http.Error(w, "unknown time zone: "+tz, http.StatusBadRequest)
+			return
+		}
+		platform.Reply(w, r, runSlashCommand(critters, form.Get("text"), t), logger)
+	}
+}
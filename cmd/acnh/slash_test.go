@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func slashCritters() ACNH {
+	critters := ACNH{
+		Bugs: []Bug{{ID: "tarantula", Name: "Tarantula", Price: 8000, Location: "On the ground", Months: []int{0, 1, 2, 3, 10, 11}, Hours: []int{19, 20, 21, 22, 23, 0, 1, 2, 3}}},
+		Fishes: []Fish{
+			{ID: "coelacanth", Name: "Coelacanth", Price: 15000, Location: "Sea", Months: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, Hours: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23}, Requirement: "Rain"},
+		},
+		Catalog: []CatalogCategory{{ID: "umbrellas", Name: "Umbrellas", Items: []CatalogItem{{Name: "Tarantula umbrella", SellPrice: Price{Amount: 100}}}}},
+	}
+	for i := range critters.Bugs {
+		setHourMap(&critters.Bugs[i])
+	}
+	for i := range critters.Fishes {
+		setHourMap(&critters.Fishes[i])
+	}
+	return critters
+}
+
+func TestRunSlashCommand(t *testing.T) {
+	critters := slashCritters()
+	night := time.Date(2020, time.January, 5, 21, 0, 0, 0, time.UTC)
+
+	reply := runSlashCommand(critters, "/acnh now fish", night)
+	if len(reply.Lines) != 1 || !strings.HasPrefix(reply.Lines[0], "Coelacanth: 15000 bells, Sea, all day") {
+		t.Errorf("expected just the coelacanth, got %+v", reply)
+	}
+	reply = runSlashCommand(critters, "now bugs", night)
+	if len(reply.Lines) != 1 || !strings.HasSuffix(reply.Lines[0], "until 4AM") {
+		t.Errorf("expected the tarantula until 4AM, got %+v", reply)
+	}
+
+	reply = runSlashCommand(critters, "when tarantla south", night)
+	want := []string{"Months: May to October", "Hours: 7PM to 4AM", "Where: On the ground", "Out of season until May."}
+	if reply.Title != "Tarantula (Southern hemisphere)" || strings.Join(reply.Lines, "|") != strings.Join(want, "|") {
+		t.Errorf("expected southern tarantula times, got %+v", reply)
+	}
+	reply = runSlashCommand(critters, "when coelacanth", night)
+	if reply.Lines[0] != "Months: all year" || reply.Lines[len(reply.Lines)-1] != "Needs: Rain" {
+		t.Errorf("expected a year-round coelacanth that needs rain, got %+v", reply)
+	}
+
+	reply = runSlashCommand(critters, "price tarantula", night)
+	if len(reply.Lines) != 1 || reply.Lines[0] != "Tarantula sells for 8000 bells" {
+		t.Errorf("expected the exact match only, got %+v", reply)
+	}
+	reply = runSlashCommand(critters, "price umbrella", night)
+	if len(reply.Lines) != 1 || reply.Lines[0] != "Tarantula umbrella (Umbrellas) sells for 100 bells" {
+		t.Errorf("expected catalog items to have prices too, got %+v", reply)
+	}
+
+	if reply := runSlashCommand(critters, "dance", night); !strings.Contains(reply.Title, slashHelp) {
+		t.Errorf("expected help for unknown commands, got %+v", reply)
+	}
+}
+
+func TestSlashHandlerVerifiesSignatures(t *testing.T) {
+	platforms := map[string]SlashPlatform{
+		"slack":   {Verifier: slackVerifier{"slack secret"}, Reply: slackReply},
+		"generic": {Verifier: hmacVerifier{"bot secret"}, Reply: genericReply},
+	}
+	h := slashHandler(slashCritters(), platforms, nopLogger{})
+	body := url.Values{"command": {"/acnh"}, "text": {"price tarantula"}}.Encode()
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	post := func(platform string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/slash/"+platform, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	mac := hmac.New(sha256.New, []byte("slack secret"))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+	w := post("slack", map[string]string{"X-Slack-Request-Timestamp": ts, "X-Slack-Signature": "v0=" + hex.EncodeToString(mac.Sum(nil))})
+	var slack struct {
+		ResponseType string `json:"response_type"`
+		Blocks       []struct {
+			Text struct{ Text string } `json:"text"`
+		} `json:"blocks"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&slack); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected a reply, got %d: %v", w.Code, err)
+	}
+	if slack.ResponseType != "in_channel" || len(slack.Blocks) != 2 || slack.Blocks[1].Text.Text != "• Tarantula sells for 8000 bells" {
+		t.Errorf("unexpected slack reply %+v", slack)
+	}
+	if w := post("slack", map[string]string{"X-Slack-Request-Timestamp": ts, "X-Slack-Signature": "v0=nope"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected a bad signature to be refused, got %d", w.Code)
+	}
+
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	if w := post("generic", map[string]string{"X-ACNH-Timestamp": old, signatureHeader: sign("bot secret", []byte(old+":"+body))}); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected an old signature to be refused, got %d", w.Code)
+	}
+	w = post("generic", map[string]string{"X-ACNH-Timestamp": ts, signatureHeader: sign("bot secret", []byte(ts+":"+body)), "Accept": "text/plain"})
+	if w.Code != http.StatusOK || w.Body.String() != "Prices for tarantula\n• Tarantula sells for 8000 bells\n" {
+		t.Errorf("expected a plain text reply, got %d: %q", w.Code, w.Body.String())
+	}
+	if w := post("discord", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected unconfigured platforms not to exist, got %d", w.Code)
+	}
+}
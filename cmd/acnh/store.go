@@ -0,0 +1,354 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+)
+
+const userCookie = "acnh_user"
+
+// Store keeps per-user state in memory and writes all of it out to a single
+// JSON file whenever something changes.  There aren't that many of us, so
+// this is plenty.
+type Store struct {
+	path   string
+	mu     sync.Mutex
+	users  map[string]*User
+	groups map[string]*Group
+	// tokens are the unused login links, keyed by the hash of the token.
+	// They're only good for a few minutes so they aren't saved.
+	tokens map[string]loginToken
+}
+
+// User is everything tracked server-side for one person: their islands,
+// which one they're looking at, and their account if they've registered one.
+type User struct {
+	Islands map[string]*Island `json:"islands"`
+	Current string             `json:"current"`
+
+	// Username is empty for anonymous users, who are only known by their
+	// cookie.  PasswordHash is empty for users who only log in with links.
+	Username     string `json:"username,omitempty"`
+	PasswordHash []byte `json:"password_hash,omitempty"`
+	Admin        bool   `json:"admin,omitempty"`
+	// Sessions is keyed by the hash of the session token, so the store file
+	// on its own isn't enough to log in as anybody.
+	Sessions map[string]Session `json:"sessions,omitempty"`
+	// CalendarTokenHash is the hash of the token that lets calendar apps
+	// fetch the user's feed.  CalendarToken is the token itself, which is
+	// how it used to be kept; it gets hashed when the store is loaded.
+	CalendarTokenHash string `json:"calendar_token_hash,omitempty"`
+	CalendarToken     string `json:"calendar_token,omitempty"`
+	// Language is the code of the language the site's shown in, or empty to
+	// go by what the browser asks for.
+	Language string `json:"language,omitempty"`
+
+	// Everything below was tracked per user before there were islands.  It
+	// gets moved into the user's first island when the store is loaded.
+	Donated   map[string]map[string]bool `json:"donated,omitempty"`
+	Residents map[string]bool            `json:"residents,omitempty"`
+	Learned   map[string]bool            `json:"learned,omitempty"`
+	Turnips   map[string]TurnipWeek      `json:"turnips,omitempty"`
+}
+
+// Island is one island's settings and progress.  Donated maps a category
+// ("bugs", "fossils", ...) to the set of things donated in it: IDs for
+// critters, and names for everything else.
+type Island struct {
+	ID         string                     `json:"id"`
+	Name       string                     `json:"name"`
+	Hemisphere Hemisphere                 `json:"hemisphere"`
+	Timezone   string                     `json:"timezone"`
+	Donated    map[string]map[string]bool `json:"donated"`
+	Residents  map[string]bool            `json:"residents,omitempty"`
+	Learned    map[string]bool            `json:"learned,omitempty"`
+	// Turnips is keyed by the date of the Sunday the week starts on.
+	Turnips  map[string]TurnipWeek `json:"turnips,omitempty"`
+	Webhooks []Webhook             `json:"webhooks,omitempty"`
+}
+
+func loadStore(path string) (*Store, error) {
+	s := &Store{
+		path:   path,
+		users:  make(map[string]*User),
+		groups: make(map[string]*Group),
+		tokens: make(map[string]loginToken),
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	// Before there were groups the file was just the users.
+	if _, ok := raw["users"]; !ok {
+		if err := json.Unmarshal(b, &s.users); err != nil {
+			return nil, err
+		}
+	} else {
+		var f storeFile
+		if err := json.Unmarshal(b, &f); err != nil {
+			return nil, err
+		}
+		if f.Users != nil {
+			s.users = f.Users
+		}
+		if f.Groups != nil {
+			s.groups = f.Groups
+		}
+	}
+	for _, u := range s.users {
+		u.migrate()
+		if u.CalendarToken != "" {
+			u.CalendarTokenHash = hashToken(u.CalendarToken)
+			u.CalendarToken = ""
+		}
+	}
+	return s, nil
+}
+
+// migrate moves anything tracked from before islands into an island of its
+// own.
+func (u *User) migrate() {
+	if len(u.Islands) > 0 {
+		return
+	}
+	i := u.addIsland(firstIslandID, defaultIslandName, Northern, defaultTimezone)
+	if u.Donated != nil {
+		i.Donated = u.Donated
+	}
+	i.Residents = u.Residents
+	i.Learned = u.Learned
+	i.Turnips = u.Turnips
+	u.Donated, u.Residents, u.Learned, u.Turnips = nil, nil, nil, nil
+}
+
+// view calls fn with the island's data while holding the lock.  fn must not
+// hang on to i or anything in it.  Users who haven't saved anything yet get
+// an empty island that isn't kept, so looking doesn't add anybody to the
+// store.
+func (s *Store) view(p Profile, fn func(i *Island)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.lookupIsland(p))
+}
+
+// update calls fn with the island's data while holding the lock, then saves.
+func (s *Store) update(p Profile, fn func(i *Island)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.island(p))
+	return s.save()
+}
+
+// user returns the user with the given ID, creating them if need be.  Only
+// things that are about to save should call it.  The lock must be held.
+func (s *Store) user(userID string) *User {
+	if _, ok := s.users[userID]; !ok {
+		s.users[userID] = &User{}
+	}
+	return s.lookup(userID)
+}
+
+// lookup returns the user with the given ID, or if there isn't one, a new
+// user that isn't added to the store.  The lock must be held.
+func (s *Store) lookup(userID string) *User {
+	u, ok := s.users[userID]
+	if !ok {
+		u = &User{}
+	}
+	u.migrate()
+	return u
+}
+
+// island returns the island the profile asks for, or the user's current one
+// if it doesn't ask for one they have, creating the user if need be.  The
+// lock must be held.
+func (s *Store) island(p Profile) *Island {
+	return pickIsland(s.user(p.User), p.Island)
+}
+
+// lookupIsland is island for reading: it doesn't add the user to the store.
+// The lock must be held.
+func (s *Store) lookupIsland(p Profile) *Island {
+	return pickIsland(s.lookup(p.User), p.Island)
+}
+
+func pickIsland(u *User, id string) *Island {
+	i, ok := u.Islands[id]
+	if !ok {
+		i, ok = u.Islands[u.Current]
+	}
+	if !ok {
+		i = u.sortedIslands()[0]
+		u.Current = i.ID
+	}
+	if i.Donated == nil {
+		i.Donated = make(map[string]map[string]bool)
+	}
+	return i
+}
+
+// storeFile is what's in the store's file.
+type storeFile struct {
+	Users  map[string]*User  `json:"users"`
+	Groups map[string]*Group `json:"groups"`
+}
+
+// save writes the whole store to a temp file and renames it over the old
+// one so a crash halfway through doesn't eat everybody's progress.  The lock
+// must be held.
+func (s *Store) save() error {
+	b, err := json.Marshal(storeFile{Users: s.users, Groups: s.groups})
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Donated returns a copy of the set of names the user has donated in the
+// given category.
+func (s *Store) Donated(p Profile, category string) map[string]bool {
+	var donated map[string]bool
+	s.view(p, func(i *Island) {
+		donated = copySet(i.Donated[category])
+	})
+	return donated
+}
+
+// AllDonated returns every category's donated names, sorted.
+func (s *Store) AllDonated(p Profile) map[string][]string {
+	var all map[string][]string
+	s.view(p, func(i *Island) {
+		all = i.donatedLists()
+	})
+	return all
+}
+
+// donatedLists is the island's donated names in each category, sorted.
+func (i *Island) donatedLists() map[string][]string {
+	all := make(map[string][]string)
+	for category, names := range i.Donated {
+		list := []string{}
+		for name, ok := range names {
+			if ok {
+				list = append(list, name)
+			}
+		}
+		sort.Strings(list)
+		all[category] = list
+	}
+	return all
+}
+
+func (s *Store) SetDonated(p Profile, category, name string, donated bool) error {
+	return s.update(p, func(i *Island) {
+		if i.Donated[category] == nil {
+			i.Donated[category] = make(map[string]bool)
+		}
+		if donated {
+			i.Donated[category][name] = true
+		} else {
+			delete(i.Donated[category], name)
+		}
+	})
+}
+
+// tracked is the set of keys the island tracks for category: its residents
+// for "villagers", its learned recipes for "recipes", and otherwise what's
+// been donated or collected.
+func (i *Island) tracked(category string) map[string]bool {
+	switch category {
+	case "villagers":
+		return i.Residents
+	case "recipes":
+		return i.Learned
+	}
+	return i.Donated[category]
+}
+
+// renameKeys moves what's tracked from old keys to new ones: renames maps a
+// category to what each old key is now.  It's for when the way things are
+// tracked changes, so it's done to every island of every user, and saved if
+// anything moved.
+func (s *Store) renameKeys(renames map[string]map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	for _, u := range s.users {
+		for _, i := range u.Islands {
+			for category, moves := range renames {
+				names := i.tracked(category)
+				for old, to := range moves {
+					ok, found := names[old]
+					if !found {
+						continue
+					}
+					delete(names, old)
+					if ok {
+						names[to] = true
+					}
+					changed = true
+				}
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// copySet copies a set, leaving out anything set to false.
+func copySet(set map[string]bool) map[string]bool {
+	c := make(map[string]bool)
+	for k, ok := range set {
+		if ok {
+			c[k] = true
+		}
+	}
+	return c
+}
+
+// userID returns the ID of the person making the request: the account
+// they're logged in to, or else the one in their cookie.  Anybody without a
+// cookie, or whose cookie belongs to an account they've logged out of, gets
+// a new one.
+func (s *Store) userID(w http.ResponseWriter, r *http.Request) string {
+	if id, _, ok := s.sessionFor(r); ok {
+		return id
+	}
+	if c, err := r.Cookie(userCookie); err == nil && c.Value != "" && !s.registered(c.Value) {
+		return c.Value
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// Not much we can do here; everybody without a cookie shares.
+		return "anonymous"
+	}
+	id := hex.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     userCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   10 * 365 * 24 * 60 * 60,
+		HttpOnly: true,
+	})
+	return id
+}
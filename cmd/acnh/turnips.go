@@ -0,0 +1,545 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TurnipPattern is one of the four ways turnip prices can go over a week.
+type TurnipPattern string
+
+const (
+	Fluctuating TurnipPattern = "fluctuating"
+	LargeSpike  TurnipPattern = "large_spike"
+	Decreasing  TurnipPattern = "decreasing"
+	SmallSpike  TurnipPattern = "small_spike"
+)
+
+// turnipPatterns is in the order the game numbers them, which is also the
+// order of turnipTransitions.
+var turnipPatterns = []TurnipPattern{Fluctuating, LargeSpike, Decreasing, SmallSpike}
+
+// turnipTransitions[i][j] is the chance of pattern j following pattern i.
+var turnipTransitions = [4][4]float64{
+	{0.20, 0.30, 0.15, 0.35},
+	{0.50, 0.05, 0.20, 0.25},
+	{0.25, 0.45, 0.05, 0.25},
+	{0.45, 0.25, 0.15, 0.15},
+}
+
+var turnipSlotNames = []string{
+	"Mon AM", "Mon PM", "Tue AM", "Tue PM", "Wed AM", "Wed PM",
+	"Thu AM", "Thu PM", "Fri AM", "Fri PM", "Sat AM", "Sat PM",
+}
+
+var errUnknownPattern = errors.New("unknown turnip pattern")
+
+func (p TurnipPattern) String() string {
+	switch p {
+	case Fluctuating:
+		return "Fluctuating"
+	case LargeSpike:
+		return "Large spike"
+	case Decreasing:
+		return "Decreasing"
+	case SmallSpike:
+		return "Small spike"
+	}
+	return "Unknown"
+}
+
+func parseTurnipPattern(s string) (TurnipPattern, error) {
+	if s == "" {
+		return "", nil
+	}
+	for _, p := range turnipPatterns {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", errUnknownPattern
+}
+
+// TurnipWeek is what someone has entered for a single week.  Prices are
+// Monday AM through Saturday PM, with 0 for "don't know".  A zero BuyPrice
+// means Daisy Mae's price wasn't written down.
+type TurnipWeek struct {
+	BuyPrice        int           `json:"buy_price"`
+	Prices          [12]int       `json:"prices"`
+	PreviousPattern TurnipPattern `json:"previous_pattern,omitempty"`
+	FirstTime       bool          `json:"first_time,omitempty"`
+}
+
+type PatternPrediction struct {
+	Pattern     TurnipPattern `json:"pattern"`
+	Probability float64       `json:"probability"`
+	Min         [12]int       `json:"min"`
+	Max         [12]int       `json:"max"`
+}
+
+func (p PatternPrediction) Name() string {
+	return p.Pattern.String()
+}
+
+func (p PatternPrediction) Percent() string {
+	return fmt.Sprintf("%.1f%%", p.Probability*100)
+}
+
+type TurnipSlot struct {
+	Name string `json:"name"`
+	// Price is what was entered, or 0.
+	Price     int  `json:"price,omitempty"`
+	Min       int  `json:"min"`
+	Max       int  `json:"max"`
+	Remaining bool `json:"remaining"`
+}
+
+// TurnipPrediction is every pattern that could still be happening given a
+// week's prices, and what each slot could still turn out to be.  If nothing
+// fits (usually a typo) Patterns is empty.
+type TurnipPrediction struct {
+	Patterns []PatternPrediction `json:"patterns"`
+	Slots    []TurnipSlot        `json:"slots"`
+}
+
+// segment is a run of half-day slots that all get priced the same way.
+// Rates are multiples of the base price.
+type segment struct {
+	kind   segmentKind
+	length int
+	// Starting rate range.
+	lo, hi float64
+	// For decreasing segments, how much the rate drops each slot.
+	dropMin, dropMax float64
+}
+
+type segmentKind int
+
+const (
+	// Every slot gets its own random rate between lo and hi.
+	randomSegment segmentKind = iota
+	// The rate starts between lo and hi and drops every slot.
+	decreasingSegment
+	// The three slots at the top of a small spike.  The middle one is the
+	// peak rate, between lo and hi, and the ones either side are a bit less
+	// than it.
+	smallPeakSegment
+)
+
+// turnipScenario is one specific way a pattern could play out, along with
+// how likely it is relative to the other ways the same pattern could.
+type turnipScenario struct {
+	pattern  TurnipPattern
+	chance   float64
+	segments []segment
+}
+
+// turnipScenarios lists every way every pattern can play out, following the
+// game's own price generation code.
+func turnipScenarios() []turnipScenario {
+	var scenarios []turnipScenario
+
+	for _, decLen1 := range []int{2, 3} {
+		for hiLen1 := 0; hiLen1 <= 6; hiLen1++ {
+			hiLen2and3 := 7 - hiLen1
+			for hiLen3 := 0; hiLen3 < hiLen2and3; hiLen3++ {
+				scenarios = append(scenarios, turnipScenario{
+					pattern: Fluctuating,
+					chance:  1.0 / 2 / 7 / float64(hiLen2and3),
+					segments: []segment{
+						{kind: randomSegment, length: hiLen1, lo: 0.9, hi: 1.4},
+						{kind: decreasingSegment, length: decLen1, lo: 0.6, hi: 0.8, dropMin: 0.04, dropMax: 0.1},
+						{kind: randomSegment, length: hiLen2and3 - hiLen3, lo: 0.9, hi: 1.4},
+						{kind: decreasingSegment, length: 5 - decLen1, lo: 0.6, hi: 0.8, dropMin: 0.04, dropMax: 0.1},
+						{kind: randomSegment, length: hiLen3, lo: 0.9, hi: 1.4},
+					},
+				})
+			}
+		}
+	}
+
+	// peakStart and the loops below count from 2 like the game does, since
+	// it stores Sunday's two (empty) slots at the front.
+	for peakStart := 3; peakStart <= 9; peakStart++ {
+		scenarios = append(scenarios, turnipScenario{
+			pattern: LargeSpike,
+			chance:  1.0 / 7,
+			segments: []segment{
+				{kind: decreasingSegment, length: peakStart - 2, lo: 0.85, hi: 0.9, dropMin: 0.03, dropMax: 0.05},
+				{kind: randomSegment, length: 1, lo: 0.9, hi: 1.4},
+				{kind: randomSegment, length: 1, lo: 1.4, hi: 2.0},
+				{kind: randomSegment, length: 1, lo: 2.0, hi: 6.0},
+				{kind: randomSegment, length: 1, lo: 1.4, hi: 2.0},
+				{kind: randomSegment, length: 1, lo: 0.9, hi: 1.4},
+				{kind: randomSegment, length: 9 - peakStart, lo: 0.4, hi: 0.9},
+			},
+		})
+	}
+
+	scenarios = append(scenarios, turnipScenario{
+		pattern: Decreasing,
+		chance:  1,
+		segments: []segment{
+			{kind: decreasingSegment, length: 12, lo: 0.85, hi: 0.9, dropMin: 0.03, dropMax: 0.05},
+		},
+	})
+
+	for peakStart := 2; peakStart <= 9; peakStart++ {
+		scenarios = append(scenarios, turnipScenario{
+			pattern: SmallSpike,
+			chance:  1.0 / 8,
+			segments: []segment{
+				{kind: decreasingSegment, length: peakStart - 2, lo: 0.4, hi: 0.9, dropMin: 0.03, dropMax: 0.05},
+				{kind: randomSegment, length: 2, lo: 0.9, hi: 1.4},
+				{kind: smallPeakSegment, length: 3, lo: 1.4, hi: 2.0},
+				{kind: decreasingSegment, length: 9 - peakStart, lo: 0.4, hi: 0.9, dropMin: 0.03, dropMax: 0.05},
+			},
+		})
+	}
+
+	return scenarios
+}
+
+// turnipPriors returns the chance of each pattern (in turnipPatterns order)
+// given last week's.  If last week's isn't known, it's the long run average.
+func turnipPriors(previous TurnipPattern, firstTime bool) [4]float64 {
+	if firstTime {
+		// Your first week always gets a small spike.
+		return [4]float64{0, 0, 0, 1}
+	}
+	for i, p := range turnipPatterns {
+		if p == previous {
+			return turnipTransitions[i]
+		}
+	}
+	priors := [4]float64{0.25, 0.25, 0.25, 0.25}
+	for n := 0; n < 100; n++ {
+		var next [4]float64
+		for i := range priors {
+			for j := range next {
+				next[j] += priors[i] * turnipTransitions[i][j]
+			}
+		}
+		priors = next
+	}
+	return priors
+}
+
+// price is what the game would charge for the given rate.
+func price(rate float64, base int) int {
+	// The tiny fudge keeps floating point noise from pushing exact
+	// multiples up a bell.
+	return int(math.Ceil(rate*float64(base) - 1e-9))
+}
+
+// fit works out the lowest and highest price each slot could have in a
+// scenario with the given base price, narrowing things down with whatever
+// prices are known.  ok is false if the known prices can't happen in this
+// scenario.
+func (s turnipScenario) fit(base int, prices [12]int) (min, max [12]int, ok bool) {
+	b := float64(base)
+	slot := 0
+	for _, seg := range s.segments {
+		switch seg.kind {
+		case randomSegment:
+			for i := 0; i < seg.length; i++ {
+				min[slot], max[slot] = price(seg.lo, base), price(seg.hi, base)
+				if p := prices[slot]; p != 0 && (p < min[slot] || p > max[slot]) {
+					return min, max, false
+				}
+				slot++
+			}
+		case decreasingSegment:
+			lo, hi := seg.lo, seg.hi
+			for i := 0; i < seg.length; i++ {
+				min[slot], max[slot] = price(lo, base), price(hi, base)
+				if p := prices[slot]; p != 0 {
+					if p < min[slot] || p > max[slot] {
+						return min, max, false
+					}
+					// Only rates in ((p-1)/base, p/base] give this price.
+					lo = math.Max(lo, float64(p-1)/b)
+					hi = math.Min(hi, float64(p)/b)
+				}
+				lo -= seg.dropMax
+				hi -= seg.dropMin
+				slot++
+			}
+		case smallPeakSegment:
+			lo, hi := seg.lo, seg.hi
+			// The peak itself pins down the rate...
+			if p := prices[slot+1]; p != 0 {
+				if p < price(lo, base) || p > price(hi, base) {
+					return min, max, false
+				}
+				lo = math.Max(lo, float64(p-1)/b)
+				hi = math.Min(hi, float64(p)/b)
+			}
+			// ...and the slots either side have to come in under it.
+			for _, side := range []int{slot, slot + 2} {
+				if p := prices[side]; p != 0 {
+					lo = math.Max(lo, float64(p)/b)
+				}
+			}
+			if lo > hi {
+				return min, max, false
+			}
+			for _, side := range []int{slot, slot + 2} {
+				min[side], max[side] = price(seg.lo, base)-1, price(hi, base)-1
+				if p := prices[side]; p != 0 && (p < min[side] || p > max[side]) {
+					return min, max, false
+				}
+			}
+			min[slot+1], max[slot+1] = price(lo, base), price(hi, base)
+			slot += 3
+		}
+	}
+	return min, max, true
+}
+
+// predictTurnips works out which patterns could be happening this week and
+// how likely each is.  Each scenario that fits the known prices counts for its
+// prior chance; this doesn't try to weigh how snugly the prices fit.
+func predictTurnips(week TurnipWeek) TurnipPrediction {
+	priors := turnipPriors(week.PreviousPattern, week.FirstTime)
+	bases := []int{week.BuyPrice}
+	if week.BuyPrice < 90 || week.BuyPrice > 110 {
+		// Daisy Mae always sells for 90 to 110.  If we don't know (or were
+		// told something silly), try them all.
+		bases = nil
+		for b := 90; b <= 110; b++ {
+			bases = append(bases, b)
+		}
+	}
+
+	byPattern := make(map[TurnipPattern]*PatternPrediction)
+	var total float64
+	for _, scenario := range turnipScenarios() {
+		var prior float64
+		for i, p := range turnipPatterns {
+			if p == scenario.pattern {
+				prior = priors[i]
+			}
+		}
+		if prior == 0 {
+			continue
+		}
+		for _, base := range bases {
+			min, max, ok := scenario.fit(base, week.Prices)
+			if !ok {
+				continue
+			}
+			chance := prior * scenario.chance / float64(len(bases))
+			total += chance
+			pp, seen := byPattern[scenario.pattern]
+			if !seen {
+				pp = &PatternPrediction{Pattern: scenario.pattern, Min: min, Max: max}
+				byPattern[scenario.pattern] = pp
+			}
+			pp.Probability += chance
+			for i := range min {
+				if min[i] < pp.Min[i] {
+					pp.Min[i] = min[i]
+				}
+				if max[i] > pp.Max[i] {
+					pp.Max[i] = max[i]
+				}
+			}
+		}
+	}
+
+	lastKnown := -1
+	for i, p := range week.Prices {
+		if p != 0 {
+			lastKnown = i
+		}
+	}
+
+	prediction := TurnipPrediction{}
+	for i, name := range turnipSlotNames {
+		prediction.Slots = append(prediction.Slots, TurnipSlot{
+			Name:      name,
+			Price:     week.Prices[i],
+			Min:       math.MaxInt32,
+			Remaining: i > lastKnown,
+		})
+	}
+	for _, p := range turnipPatterns {
+		pp, ok := byPattern[p]
+		if !ok {
+			continue
+		}
+		pp.Probability /= total
+		prediction.Patterns = append(prediction.Patterns, *pp)
+		for i := range prediction.Slots {
+			if pp.Min[i] < prediction.Slots[i].Min {
+				prediction.Slots[i].Min = pp.Min[i]
+			}
+			if pp.Max[i] > prediction.Slots[i].Max {
+				prediction.Slots[i].Max = pp.Max[i]
+			}
+		}
+	}
+	if len(prediction.Patterns) == 0 {
+		for i := range prediction.Slots {
+			prediction.Slots[i].Min = 0
+		}
+	}
+
+	return prediction
+}
+
+// turnipWeekStart returns the Sunday a week starts on, which is what weeks
+// are keyed by.
+func turnipWeekStart(t time.Time) string {
+	return t.AddDate(0, 0, -int(t.Weekday())).Format("2006-01-02")
+}
+
+func (s *Store) TurnipWeek(p Profile, week string) TurnipWeek {
+	var tw TurnipWeek
+	s.view(p, func(i *Island) {
+		tw = i.Turnips[week]
+	})
+	return tw
+}
+
+func (s *Store) SetTurnipWeek(p Profile, week string, tw TurnipWeek) error {
+	return s.update(p, func(i *Island) {
+		if i.Turnips == nil {
+			i.Turnips = make(map[string]TurnipWeek)
+		}
+		i.Turnips[week] = tw
+	})
+}
+
+// parseTurnipWeek reads a week's prices out of a form.  Blank fields are
+// left as 0.
+func parseTurnipWeek(r *http.Request) (TurnipWeek, error) {
+	var tw TurnipWeek
+	var err error
+	intField := func(name string) (int, error) {
+		v := r.FormValue(name)
+		if v == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 1000 {
+			return 0, fmt.Errorf("%s must be a price between 0 and 1000", name)
+		}
+		return n, nil
+	}
+	if tw.BuyPrice, err = intField("buy"); err != nil {
+		return tw, err
+	}
+	for i := range tw.Prices {
+		if tw.Prices[i], err = intField(fmt.Sprintf("price%d", i)); err != nil {
+			return tw, err
+		}
+	}
+	if tw.PreviousPattern, err = parseTurnipPattern(r.FormValue("previous_pattern")); err != nil {
+		return tw, err
+	}
+	tw.FirstTime, _ = strconv.ParseBool(r.FormValue("first_time"))
+	return tw, nil
+}
+
+// requestedWeek returns the week asked for in the "week" parameter, or the
+// current one on the profile's island.
+func requestedWeek(r *http.Request, store *Store, id Profile) (string, error) {
+	if week := r.FormValue("week"); week != "" {
+		t, err := time.Parse("2006-01-02", week)
+		if err != nil {
+			return "", err
+		}
+		return turnipWeekStart(t), nil
+	}
+	t, err := store.Now(id)
+	if err != nil {
+		return "", err
+	}
+	return turnipWeekStart(t), nil
+}
+
+type TurnipsPage struct {
+	Week       string           `json:"week"`
+	Entry      TurnipWeek       `json:"entry"`
+	Prediction TurnipPrediction `json:"prediction"`
+	Patterns   []TurnipPattern  `json:"-"`
+}
+
+func turnipsPage(store *Store, id Profile, week string) TurnipsPage {
+	entry := store.TurnipWeek(id, week)
+	return TurnipsPage{
+		Week:       week,
+		Entry:      entry,
+		Prediction: predictTurnips(entry),
+		Patterns:   turnipPatterns,
+	}
+}
+
+// turnipsHandler shows a week's prices and predictions, and saves new prices
+// on POST.
+func turnipsHandler(languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		week, err := requestedWeek(r, store, id)
+		if err != nil {
+			http.Error(w, "week must look like 2020-04-05", http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodPost {
+			tw, err := parseTurnipWeek(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := store.SetTurnipWeek(id, week, tw); err != nil {
+				logger.Log("failed saving turnip prices", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, "/turnips?week="+week, http.StatusSeeOther)
+			return
+		}
+		if err := render(w, r, languages, store, id, tmpl, http.StatusOK, turnipsPage(store, id, week)); err != nil {
+			logger.Log("failed rendering turnips", "error", err)
+		}
+	}
+}
+
+// turnipsAPIHandler is turnipsHandler for scripts: POST saves the week, and
+// both return the week's prices and predictions as JSON.
+func turnipsAPIHandler(store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		week, err := requestedWeek(r, store, id)
+		if err != nil {
+			http.Error(w, "week must look like 2020-04-05", http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			tw, err := parseTurnipWeek(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := store.SetTurnipWeek(id, week, tw); err != nil {
+				logger.Log("failed saving turnip prices", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, turnipsPage(store, id, week), logger)
+	}
+}
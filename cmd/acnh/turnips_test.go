@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTurnipPriors(t *testing.T) {
+	priors := turnipPriors("", false)
+	var sum float64
+	for _, p := range priors {
+		sum += p
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("expected priors to add up to 1, got %f", sum)
+	}
+	if priors := turnipPriors(Decreasing, false); priors[1] != 0.45 {
+		t.Errorf("expected a 45%% chance of a large spike after a decreasing week, got %f", priors[1])
+	}
+	if priors := turnipPriors(Decreasing, true); priors[3] != 1 {
+		t.Errorf("expected first time buyers to always get a small spike, got %v", priors)
+	}
+}
+
+func TestPredictTurnips(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Week     TurnipWeek
+		Expected []TurnipPattern
+	}{
+		{
+			"nothing known",
+			TurnipWeek{},
+			[]TurnipPattern{Fluctuating, LargeSpike, Decreasing, SmallSpike},
+		},
+		{
+			"large spike on Wednesday",
+			TurnipWeek{BuyPrice: 100, Prices: [12]int{86, 82, 78, 120, 190, 550}},
+			[]TurnipPattern{LargeSpike},
+		},
+		{
+			"steady decline",
+			TurnipWeek{BuyPrice: 100, Prices: [12]int{88, 84, 80, 76, 72, 68, 64, 60}},
+			[]TurnipPattern{Decreasing},
+		},
+		{
+			"small spike",
+			TurnipWeek{BuyPrice: 100, Prices: [12]int{80, 76, 72, 110, 120, 150, 180, 150}},
+			[]TurnipPattern{SmallSpike},
+		},
+		{
+			"fluctuating",
+			TurnipWeek{BuyPrice: 100, Prices: [12]int{120, 130, 75, 70, 110}},
+			[]TurnipPattern{Fluctuating},
+		},
+		{
+			"typo",
+			TurnipWeek{BuyPrice: 100, Prices: [12]int{9000}},
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		got := predictTurnips(test.Week)
+		if len(got.Patterns) != len(test.Expected) {
+			t.Errorf("failed test '%s': expected %v, got %v", test.Name, test.Expected, got.Patterns)
+			continue
+		}
+		var total float64
+		for i, p := range got.Patterns {
+			total += p.Probability
+			if p.Pattern != test.Expected[i] {
+				t.Errorf("failed test '%s': expected %v, got %v", test.Name, test.Expected, got.Patterns)
+			}
+		}
+		if len(got.Patterns) > 0 && math.Abs(total-1) > 1e-9 {
+			t.Errorf("failed test '%s': probabilities add up to %f", test.Name, total)
+		}
+		for i, slot := range got.Slots {
+			if slot.Price != 0 && len(got.Patterns) > 0 && (slot.Price < slot.Min || slot.Price > slot.Max) {
+				t.Errorf("failed test '%s': %s price %d outside of %d-%d", test.Name, slot.Name, slot.Price, slot.Min, slot.Max)
+			}
+			if slot.Remaining != (i > lastKnown(test.Week.Prices)) {
+				t.Errorf("failed test '%s': %s remaining should be %t", test.Name, slot.Name, !slot.Remaining)
+			}
+		}
+	}
+}
+
+func lastKnown(prices [12]int) int {
+	last := -1
+	for i, p := range prices {
+		if p != 0 {
+			last = i
+		}
+	}
+	return last
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// maxResidents is how many villagers can live on an island at once.
+const maxResidents = 10
+
+var errTooManyResidents = errors.New("an island only has room for 10 residents")
+
+// Villager is someone who might move to your island.  BirthdayMonth is
+// zero-based, same as critter months.
+type Villager struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Species       string `json:"species"`
+	Personality   string `json:"personality"`
+	BirthdayMonth int    `json:"birthday_month"`
+	BirthdayDay   int    `json:"birthday_day"`
+	Catchphrase   string `json:"catchphrase"`
+	Hobby         string `json:"hobby"`
+	// Aliases are names the villager has gone by before.
+	Aliases  []string `json:"aliases,omitempty"`
+	Resident bool     `json:"resident"`
+}
+
+func (v Villager) DisplayBirthday() string {
+	return time.Month(v.BirthdayMonth+1).String() + " " + strconv.Itoa(v.BirthdayDay)
+}
+
+// Birthday is a villager whose birthday is coming up (or here).
+type Birthday struct {
+	Villager
+	Today bool
+}
+
+// birthdaysThisMonth returns the villagers with a birthday in t's month,
+// in date order, with residents (by ID) marked.
+func birthdaysThisMonth(villagers []Villager, residents map[string]bool, t time.Time) []Birthday {
+	var birthdays []Birthday
+	for _, v := range villagers {
+		if v.BirthdayMonth != int(t.Month())-1 {
+			continue
+		}
+		v.Resident = residents[v.ID]
+		birthdays = append(birthdays, Birthday{v, v.BirthdayDay == t.Day()})
+	}
+	sort.SliceStable(birthdays, func(i, j int) bool {
+		return birthdays[i].BirthdayDay < birthdays[j].BirthdayDay
+	})
+	return birthdays
+}
+
+// Residents returns a copy of the set of IDs of villagers living on the
+// user's island.
+func (s *Store) Residents(p Profile) map[string]bool {
+	var residents map[string]bool
+	s.view(p, func(i *Island) {
+		residents = copySet(i.Residents)
+	})
+	return residents
+}
+
+func (s *Store) SetResident(p Profile, id string, resident bool) error {
+	var err error
+	saveErr := s.update(p, func(i *Island) {
+		if i.Residents == nil {
+			i.Residents = make(map[string]bool)
+		}
+		if !resident {
+			delete(i.Residents, id)
+			return
+		}
+		if !i.Residents[id] && len(i.Residents) >= maxResidents {
+			err = errTooManyResidents
+			return
+		}
+		i.Residents[id] = true
+	})
+	if err != nil {
+		return err
+	}
+	return saveErr
+}
+
+type VillagersPage struct {
+	Villagers []Villager
+}
+
+// residentVillagers returns a copy of villagers with Resident filled in.
+func residentVillagers(villagers []Villager, residents map[string]bool) []Villager {
+	vs := make([]Villager, len(villagers))
+	for i, v := range villagers {
+		v.Resident = residents[v.ID]
+		vs[i] = v
+	}
+	return vs
+}
+
+func villagersHandler(villagers []Villager, languages Languages, store *Store, tmpl *template.Template, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		residents := store.Residents(id)
+		if err := render(w, r, languages, store, id, tmpl, http.StatusOK, VillagersPage{residentVillagers(villagers, residents)}); err != nil {
+			logger.Log("failed rendering villagers", "error", err)
+		}
+	}
+}
+
+// residentsHandler returns the user's residents on GET, and moves a villager
+// in or out on POST.
+func residentsHandler(critters ACNH, store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := store.profile(w, r)
+		switch r.Method {
+		case http.MethodGet:
+			var residents []Villager
+			for _, v := range residentVillagers(critters.Villagers, store.Residents(id)) {
+				if v.Resident {
+					residents = append(residents, v)
+				}
+			}
+			writeJSON(w, residents, logger)
+		case http.MethodPost:
+			villager := r.FormValue("id")
+			if villager == "" {
+				villager = critters.donationKey("villagers", r.FormValue("name"))
+			}
+			if !critters.hasEntry("villagers", villager) {
+				http.Error(w, "unknown villager", http.StatusBadRequest)
+				return
+			}
+			resident, err := strconv.ParseBool(r.FormValue("resident"))
+			if err != nil {
+				http.Error(w, "resident must be true or false", http.StatusBadRequest)
+				return
+			}
+			if err := store.SetResident(id, villager, resident); err != nil {
+				if errors.Is(err, errTooManyResidents) {
+					http.Error(w, err.Error(), http.StatusConflict)
+					return
+				}
+				logger.Log("failed saving resident", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
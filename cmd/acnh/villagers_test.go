@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBirthdaysThisMonth(t *testing.T) {
+	villagers := []Villager{
+		{Name: "Ankha", BirthdayMonth: 8, BirthdayDay: 22},
+		{Name: "Apollo", BirthdayMonth: 6, BirthdayDay: 4},
+		{Name: "Bob", BirthdayMonth: 0, BirthdayDay: 1},
+		{Name: "Lolly", BirthdayMonth: 2, BirthdayDay: 27},
+		{Name: "Raymond", BirthdayMonth: 9, BirthdayDay: 1},
+		{Name: "Stitches", BirthdayMonth: 1, BirthdayDay: 10},
+		{ID: "sherb", Name: "Sherb", BirthdayMonth: 0, BirthdayDay: 18},
+		{Name: "Zucker", BirthdayMonth: 2, BirthdayDay: 8},
+		{Name: "Marshal", BirthdayMonth: 8, BirthdayDay: 29},
+		{Name: "Judy", BirthdayMonth: 2, BirthdayDay: 10},
+		{Name: "Cube", BirthdayMonth: 0, BirthdayDay: 31},
+		{Name: "Diana", BirthdayMonth: 0, BirthdayDay: 4},
+	}
+	residents := map[string]bool{"sherb": true}
+
+	tests := []struct {
+		Name     string
+		Time     time.Time
+		Expected []string
+		Today    string
+	}{
+		{"first of the year", time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC), []string{"Bob", "Diana", "Sherb", "Cube"}, "Bob"},
+		{"last of January", time.Date(2020, time.January, 31, 23, 59, 0, 0, time.UTC), []string{"Bob", "Diana", "Sherb", "Cube"}, "Cube"},
+		{"first of February", time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC), []string{"Stitches"}, ""},
+		{"last of December", time.Date(2020, time.December, 31, 23, 59, 0, 0, time.UTC), nil, ""},
+		{"March, out of order", time.Date(2020, time.March, 10, 12, 0, 0, 0, time.UTC), []string{"Zucker", "Judy", "Lolly"}, "Judy"},
+	}
+
+	for _, test := range tests {
+		birthdays := birthdaysThisMonth(villagers, residents, test.Time)
+		var got []string
+		today := ""
+		for _, b := range birthdays {
+			got = append(got, b.Name)
+			if b.Today {
+				today = b.Name
+			}
+			if b.Resident != residents[b.ID] {
+				t.Errorf("failed test '%s': expected %s to be a resident: %v", test.Name, b.Name, residents[b.ID])
+			}
+		}
+		if !reflect.DeepEqual(got, test.Expected) {
+			t.Errorf("failed test '%s': expected %v, got %v", test.Name, test.Expected, got)
+		}
+		if today != test.Today {
+			t.Errorf("failed test '%s': expected %q's birthday today, got %q", test.Name, test.Today, today)
+		}
+	}
+}
+
+func TestSetResident(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	p := Profile{User: "someone"}
+	for i := 0; i < maxResidents; i++ {
+		if err := store.SetResident(p, "villager "+strconv.Itoa(i), true); err != nil {
+			t.Fatalf("expected room for resident %d, got %v", i+1, err)
+		}
+	}
+
+	tests := []struct {
+		Name     string
+		Villager string
+		Resident bool
+		Err      error
+		Count    int
+	}{
+		{"11th resident", "one too many", true, errTooManyResidents, maxResidents},
+		{"already a resident", "villager 0", true, nil, maxResidents},
+		{"moving out", "villager 0", false, nil, maxResidents - 1},
+		{"moving in after", "one too many", true, nil, maxResidents},
+		{"moving out someone who isn't there", "nobody", false, nil, maxResidents},
+	}
+
+	for _, test := range tests {
+		err := store.SetResident(p, test.Villager, test.Resident)
+		if !errors.Is(err, test.Err) {
+			t.Errorf("failed test '%s': expected error %v, got %v", test.Name, test.Err, err)
+		}
+		if got := len(store.Residents(p)); got != test.Count {
+			t.Errorf("failed test '%s': expected %d residents, got %d", test.Name, test.Count, got)
+		}
+	}
+}
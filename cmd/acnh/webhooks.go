@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+const (
+	webhookAttempts = 5
+	webhookBackoff  = 2 * time.Second
+	webhookTimeout  = 10 * time.Second
+	// signatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body,
+	// keyed with the webhook's secret.
+	signatureHeader = "X-ACNH-Signature"
+)
+
+// Webhook event names, for WebhookPayload.Events.
+const (
+	EventAvailabilityChanged = "availability_changed"
+	EventUndonatedAvailable  = "undonated_available"
+	EventUndonatedLeaving    = "undonated_leaving"
+)
+
+var (
+	errUnknownWebhook = errors.New("no such webhook")
+	errWebhookURL     = errors.New("webhooks need an http or https URL")
+	errWebhookAddress = errors.New("webhooks can't go to private addresses")
+)
+
+// privateNetworks are the ranges on top of loopback and link-local that
+// belong to somebody's own network rather than the internet.
+var privateNetworks = parseCIDRs("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7")
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, n)
+	}
+	return networks
+}
+
+// publicIP reports whether ip is somewhere on the internet, rather than the
+// server itself or the network it's on.
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, n := range privateNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialPublic is a net.Dialer Control hook that refuses to connect to
+// anything but public addresses.  It sees the address after the name's been
+// looked up, so names that point somewhere private don't get through either.
+func dialPublic(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("%w: %s", errWebhookAddress, host)
+	}
+	return nil
+}
+
+// webhookClient is the client webhooks are sent with.  Anybody can add a
+// webhook, so it only connects to public addresses, and doesn't follow
+// redirects, which could point anywhere.
+func webhookClient() *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: dialPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would do the connecting, out of dialPublic's sight.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		Timeout:   webhookTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Webhook is a URL that gets told when what's around on an island changes.
+// The secret is only shown when it's created.
+type Webhook struct {
+	ID      string    `json:"id"`
+	URL     string    `json:"url"`
+	Secret  string    `json:"secret,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// WebhookCritter is a critter as it's named in webhook payloads.
+type WebhookCritter struct {
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	Price    int    `json:"price"`
+}
+
+// WebhookPayload is what gets posted at an hour boundary when something's
+// changed.  Arrived and Left are everything that came or went at the start
+// of the hour; UndonatedArrived is the ones that came that haven't been
+// donated yet, and UndonatedLeaving the ones that haven't been donated and
+// will be gone by the end of it.
+type WebhookPayload struct {
+	Events           []string         `json:"events"`
+	Island           string           `json:"island"`
+	Time             time.Time        `json:"time"`
+	Arrived          []WebhookCritter `json:"arrived"`
+	Left             []WebhookCritter `json:"left"`
+	UndonatedArrived []WebhookCritter `json:"undonated_arrived"`
+	UndonatedLeaving []WebhookCritter `json:"undonated_leaving"`
+}
+
+// hourChanges works out the payload for the start of t's hour, and whether
+// there's anything in it worth sending.  critters should already be in the
+// island's hemisphere and t in its time zone.
+func hourChanges(critters ACNH, donated map[string][]string, island string, t time.Time) (WebhookPayload, bool) {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	before, after := t.Add(-time.Hour), t.Add(time.Hour)
+	done := donatedSets(donated)
+	p := WebhookPayload{
+		Events:           []string{},
+		Island:           island,
+		Time:             t,
+		Arrived:          []WebhookCritter{},
+		Left:             []WebhookCritter{},
+		UndonatedArrived: []WebhookCritter{},
+		UndonatedLeaving: []WebhookCritter{},
+	}
+	for _, c := range critters.critters() {
+		wc := WebhookCritter{Category: c.Category, Name: c.Name, Location: c.Location, Price: c.Price}
+		now, was := season.Around(c.Months, c.HourMap, t), season.Around(c.Months, c.HourMap, before)
+		undonated := !done[c.Category][c.ID]
+		if now && !was {
+			p.Arrived = append(p.Arrived, wc)
+			if undonated {
+				p.UndonatedArrived = append(p.UndonatedArrived, wc)
+			}
+		}
+		if was && !now {
+			p.Left = append(p.Left, wc)
+		}
+		if now && undonated && !season.Around(c.Months, c.HourMap, after) {
+			p.UndonatedLeaving = append(p.UndonatedLeaving, wc)
+		}
+	}
+	if len(p.Arrived) > 0 || len(p.Left) > 0 {
+		p.Events = append(p.Events, EventAvailabilityChanged)
+	}
+	if len(p.UndonatedArrived) > 0 {
+		p.Events = append(p.Events, EventUndonatedAvailable)
+	}
+	if len(p.UndonatedLeaving) > 0 {
+		p.Events = append(p.Events, EventUndonatedLeaving)
+	}
+	return p, len(p.Events) > 0
+}
+
+// sign is the value of the signature header for body.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookSender posts payloads, trying again with a doubling wait when the
+// receiver can't be reached or has a problem on its end.  Redirects and
+// private addresses aren't worth trying again.
+type webhookSender struct {
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+	logger   Logger
+}
+
+func newWebhookSender(logger Logger) *webhookSender {
+	return &webhookSender{
+		client:   webhookClient(),
+		attempts: webhookAttempts,
+		backoff:  webhookBackoff,
+		logger:   logger,
+	}
+}
+
+func (s *webhookSender) send(hook Webhook, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	delivery := newIslandID()
+	wait := s.backoff
+	for attempt := 1; ; attempt++ {
+		err = s.post(hook, delivery, body)
+		if err == nil {
+			return nil
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) || errors.Is(err, errWebhookAddress) || attempt >= s.attempts {
+			return err
+		}
+		s.logger.Log("retrying webhook", "url", hook.URL, "attempt", attempt, "error", err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// permanentError is a response that trying again won't fix.
+type permanentError struct {
+	status int
+}
+
+func (e permanentError) Error() string {
+	return fmt.Sprintf("webhook refused with %d", e.status)
+}
+
+func (s *webhookSender) post(hook Webhook, delivery string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-ACNH-Delivery", delivery)
+	req.Header.Set(signatureHeader, sign(hook.Secret, body))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("webhook failed with %d", resp.StatusCode)
+	default:
+		return permanentError{resp.StatusCode}
+	}
+}
+
+// WebhookTarget is a webhook along with what's needed to work out what to
+// send it.
+type WebhookTarget struct {
+	Webhook    Webhook
+	Island     string
+	Timezone   string
+	Hemisphere Hemisphere
+	Donated    map[string][]string
+}
+
+// WebhookTargets returns every webhook on every island.
+func (s *Store) WebhookTargets() []WebhookTarget {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var targets []WebhookTarget
+	for _, u := range s.users {
+		for _, i := range u.Islands {
+			if len(i.Webhooks) == 0 {
+				continue
+			}
+			donated := i.donatedLists()
+			for _, hook := range i.Webhooks {
+				targets = append(targets, WebhookTarget{hook, i.Name, i.Timezone, i.Hemisphere, donated})
+			}
+		}
+	}
+	return targets
+}
+
+// Webhooks returns the island's webhooks, without their secrets.
+func (s *Store) Webhooks(p Profile) []Webhook {
+	hooks := []Webhook{}
+	s.view(p, func(i *Island) {
+		for _, hook := range i.Webhooks {
+			hook.Secret = ""
+			hooks = append(hooks, hook)
+		}
+	})
+	sort.Slice(hooks, func(a, b int) bool {
+		return hooks[a].Created.Before(hooks[b].Created)
+	})
+	return hooks
+}
+
+// AddWebhook adds a webhook to the island, making up a secret for it if it
+// wasn't given one.
+func (s *Store) AddWebhook(p Profile, rawURL, secret string) (Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Webhook{}, errWebhookURL
+	}
+	if secret == "" {
+		if secret, _, err = newToken(); err != nil {
+			return Webhook{}, err
+		}
+	}
+	hook := Webhook{ID: newIslandID(), URL: u.String(), Secret: secret, Created: time.Now().UTC()}
+	return hook, s.update(p, func(i *Island) {
+		i.Webhooks = append(i.Webhooks, hook)
+	})
+}
+
+// DeleteWebhook removes one of the island's webhooks.
+func (s *Store) DeleteWebhook(p Profile, id string) error {
+	found := false
+	err := s.update(p, func(i *Island) {
+		for n, hook := range i.Webhooks {
+			if hook.ID == id {
+				i.Webhooks = append(i.Webhooks[:n], i.Webhooks[n+1:]...)
+				found = true
+				return
+			}
+		}
+	})
+	if err == nil && !found {
+		return errUnknownWebhook
+	}
+	return err
+}
+
+// webhookScheduler checks every webhook each minute and sends it whatever
+// changed once its island's clock passes the hour.
+type webhookScheduler struct {
+	critters ACNH
+	store    *Store
+	sender   *webhookSender
+	logger   Logger
+	// last is the hour each webhook was last checked for, by ID.
+	last map[string]time.Time
+}
+
+func newWebhookScheduler(critters ACNH, store *Store, logger Logger) *webhookScheduler {
+	return &webhookScheduler{
+		critters: critters,
+		store:    store,
+		sender:   newWebhookSender(logger),
+		logger:   logger,
+		last:     make(map[string]time.Time),
+	}
+}
+
+// run checks at the top of every minute, forever.
+func (s *webhookScheduler) run() {
+	for {
+		s.tick(time.Now())
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+	}
+}
+
+// tick sends whatever's due at now.  Webhooks it hasn't seen before (like
+// everything when the server starts) wait for the next hour rather than
+// repeating one that may have already gone out.
+func (s *webhookScheduler) tick(now time.Time) {
+	seen := make(map[string]bool)
+	for _, target := range s.store.WebhookTargets() {
+		seen[target.Webhook.ID] = true
+		loc, err := time.LoadLocation(target.Timezone)
+		if err != nil {
+			s.logger.Log("failed loading timezone data", "error", err)
+			continue
+		}
+		t := now.In(loc)
+		// Some time zones are off by half an hour, so the hour has to be
+		// worked out on the island's clock.
+		hour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		last, ok := s.last[target.Webhook.ID]
+		s.last[target.Webhook.ID] = hour
+		if !ok || !hour.After(last) {
+			continue
+		}
+		c := s.critters.forHemisphere(target.Hemisphere)
+		payload, ok := hourChanges(c, target.Donated, target.Island, hour)
+		if !ok {
+			continue
+		}
+		go func(hook Webhook) {
+			if err := s.sender.send(hook, payload); err != nil {
+				s.logger.Log("failed sending webhook", "url", hook.URL, "error", err)
+			}
+		}(target.Webhook)
+	}
+	for id := range s.last {
+		if !seen[id] {
+			delete(s.last, id)
+		}
+	}
+}
+
+// webhooksHandler lists the island's webhooks on GET, adds one for "url"
+// (with an optional "secret") on POST, and deletes the one in "id" on
+// DELETE.  Only accounts get webhooks, but anybody can register one, so
+// that's not what stops the server being pointed at its own network:
+// webhookClient is.
+func webhooksHandler(store *Store, logger Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := store.CurrentAccount(r); !ok {
+			http.Error(w, "log in first", http.StatusUnauthorized)
+			return
+		}
+		id := store.profile(w, r)
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, store.Webhooks(id), logger)
+		case http.MethodPost:
+			hook, err := store.AddWebhook(id, r.FormValue("url"), r.FormValue("secret"))
+			if err != nil {
+				if errors.Is(err, errWebhookURL) {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				logger.Log("failed saving webhook", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, hook, logger)
+		case http.MethodDelete:
+			if err := store.DeleteWebhook(id, r.FormValue("id")); err != nil {
+				if errors.Is(err, errUnknownWebhook) {
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				logger.Log("failed deleting webhook", "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Log(msg string, attrs ...interface{}) error {
+	return nil
+}
+
+func webhookCritters() ACNH {
+	critters := ACNH{
+		Bugs: []Bug{
+			{ID: "ant", Name: "Ant", Months: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, Hours: []int{9, 10, 11, 12, 13, 14, 15}},
+			{ID: "bee", Name: "Bee", Months: []int{4}, Hours: []int{9, 10, 11}},
+		},
+	}
+	for i := range critters.Bugs {
+		setHourMap(&critters.Bugs[i])
+	}
+	return critters
+}
+
+func names(critters []WebhookCritter) []string {
+	n := []string{}
+	for _, c := range critters {
+		n = append(n, c.Name)
+	}
+	return n
+}
+
+func TestHourChanges(t *testing.T) {
+	critters := webhookCritters()
+	donated := map[string][]string{"bugs": {"ant"}}
+	at := func(month time.Month, hour int) time.Time {
+		return time.Date(2020, month, 4, hour, 20, 0, 0, time.UTC)
+	}
+
+	p, ok := hourChanges(critters, donated, "Home", at(time.May, 9))
+	if !ok || len(p.Arrived) != 2 || len(p.UndonatedArrived) != 1 || p.UndonatedArrived[0].Name != "Bee" {
+		t.Errorf("expected both to arrive at 9 and only the bee to be undonated, got %+v", p)
+	}
+	if !p.Time.Equal(time.Date(2020, time.May, 4, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the payload to be for the top of the hour, got %v", p.Time)
+	}
+	p, ok = hourChanges(critters, donated, "Home", at(time.May, 11))
+	if !ok || len(p.Arrived) != 0 || len(p.UndonatedLeaving) != 1 || p.Events[0] != EventUndonatedLeaving {
+		t.Errorf("expected only the bee to be about to leave at 11, got %+v", p)
+	}
+	p, ok = hourChanges(critters, donated, "Home", at(time.May, 12))
+	if !ok || names(p.Left)[0] != "Bee" || len(p.UndonatedLeaving) != 0 {
+		t.Errorf("expected the bee to have left at 12, got %+v", p)
+	}
+	if _, ok := hourChanges(critters, donated, "Home", at(time.June, 13)); ok {
+		t.Errorf("expected nothing to send when nothing changes")
+	}
+}
+
+func TestWebhookSenderRetries(t *testing.T) {
+	hook := Webhook{Secret: "shh"}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(signatureHeader) != sign(hook.Secret, body) {
+			t.Errorf("expected the body to be signed")
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer server.Close()
+	sender := &webhookSender{client: server.Client(), attempts: 3, backoff: time.Millisecond, logger: nopLogger{}}
+
+	hook.URL = server.URL
+	if err := sender.send(hook, WebhookPayload{}); err != nil || calls != 2 {
+		t.Errorf("expected to succeed on the second try, got %v after %d", err, calls)
+	}
+	calls = 1
+	hook.URL = server.URL + "/gone"
+	if err := sender.send(hook, WebhookPayload{}); err == nil || calls != 2 {
+		t.Errorf("expected to give up right away when the webhook's gone, got %v after %d", err, calls)
+	}
+}
+
+func TestWebhookSchedulerWaitsForTheHour(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	received := make(chan WebhookPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Error(err)
+		}
+		received <- p
+	}))
+	defer server.Close()
+
+	p := Profile{User: "someone"}
+	if err := store.UpdateIsland(Profile{User: "someone", Island: store.Island(p).ID}, IslandSettings{Name: "Home", Hemisphere: Northern, Timezone: "UTC"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddWebhook(p, "ftp://example.com", ""); err != errWebhookURL {
+		t.Errorf("expected only http URLs, got %v", err)
+	}
+	hook, err := store.AddWebhook(p, server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hook.Secret == "" || store.Webhooks(p)[0].Secret != "" {
+		t.Errorf("expected a secret that's only shown once")
+	}
+
+	s := newWebhookScheduler(webhookCritters(), store, nopLogger{})
+	// The test server's on loopback, which the real client won't go to.
+	s.sender.client = server.Client()
+	s.tick(time.Date(2020, time.May, 4, 8, 59, 0, 0, time.UTC))
+	s.tick(time.Date(2020, time.May, 4, 9, 0, 0, 0, time.UTC))
+	select {
+	case payload := <-received:
+		if payload.Island != "Home" || len(payload.Arrived) != 2 {
+			t.Errorf("expected both bugs to arrive on Home, got %+v", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a webhook at 9")
+	}
+	s.tick(time.Date(2020, time.May, 4, 9, 1, 0, 0, time.UTC))
+	select {
+	case payload := <-received:
+		t.Errorf("expected one webhook an hour, got another %+v", payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWebhookClientStaysPublic(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+	sender := &webhookSender{client: webhookClient(), attempts: 3, backoff: time.Millisecond, logger: nopLogger{}}
+	if err := sender.send(Webhook{URL: server.URL}, WebhookPayload{}); !errors.Is(err, errWebhookAddress) || calls != 0 {
+		t.Errorf("expected loopback to be refused without retrying, got %v after %d", err, calls)
+	}
+
+	tests := []struct {
+		IP     string
+		Public bool
+	}{
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.20.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"8.8.8.8", true},
+		{"2001:4860:4860::8888", true},
+	}
+	for _, test := range tests {
+		if got := publicIP(net.ParseIP(test.IP)); got != test.Public {
+			t.Errorf("failed test '%s': expected public %v, got %v", test.IP, test.Public, got)
+		}
+	}
+}
+
+func TestWebhookClientRefusesRedirects(t *testing.T) {
+	redirected := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/elsewhere" {
+			redirected = true
+			return
+		}
+		http.Redirect(w, r, "/elsewhere", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+	client := server.Client()
+	client.CheckRedirect = webhookClient().CheckRedirect
+	sender := &webhookSender{client: client, attempts: 3, backoff: time.Millisecond, logger: nopLogger{}}
+	var permanent permanentError
+	if err := sender.send(Webhook{URL: server.URL}, WebhookPayload{}); !errors.As(err, &permanent) || redirected {
+		t.Errorf("expected the redirect to be refused, got %v", err)
+	}
+}
+
+func TestExportLeavesOutSecrets(t *testing.T) {
+	store, cleanup := tempStore(t)
+	defer cleanup()
+	p := Profile{User: "someone"}
+	hook, err := store.AddWebhook(p, "https://example.com/hook", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := store.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), hook.Secret) || !strings.Contains(string(b), hook.URL) {
+		t.Errorf("expected the webhook without its secret, got %s", b)
+	}
+	if store.users["someone"].Islands[store.Island(p).ID].Webhooks[0].Secret != hook.Secret {
+		t.Errorf("expected exporting to leave the store's secrets alone")
+	}
+}
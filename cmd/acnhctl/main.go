@@ -0,0 +1,426 @@
+// acnhctl answers the same questions as the web app from a terminal: what's
+// out now or at some other time, what's leaving after this month, everything
+// about one critter, and marking critters donated.  It reads acnh.json
+// directly, or with -server asks a running acnh server instead.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+const usage = `usage: acnhctl [flags] <command>
+
+commands:
+  now            critters out right now
+  at <time>      critters out at a time, like "2020-05-04 21:00" or "21:00"
+  leaving        critters that won't be back next month
+  info <name>    everything about one critter
+  donate <name>  mark a critter donated
+  tui            full-screen view of what's out now, with countdowns
+
+flags:
+`
+
+// Critter is a bug, fish or sea creature as acnh.json and the server's API
+// have it, with the months for the northern hemisphere.
+type Critter struct {
+	Category    string       `json:"category"`
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Price       int          `json:"price"`
+	Months      []int        `json:"months"`
+	Hours       []int        `json:"hours"`
+	Location    string       `json:"location"`
+	ShadowSize  string       `json:"shadow_size,omitempty"`
+	Speed       string       `json:"speed,omitempty"`
+	Weather     string       `json:"weather,omitempty"`
+	Requirement string       `json:"requirement,omitempty"`
+	Aliases     []string     `json:"aliases,omitempty"`
+	HourMap     map[int]bool `json:"-"`
+}
+
+// seaCreatureLocation is where every sea creature lives.
+const seaCreatureLocation = "Sea (diving)"
+
+// critterData is the part of acnh.json (or /api/critters) acnhctl uses.
+type critterData struct {
+	Bugs         []Critter `json:"bugs"`
+	Fishes       []Critter `json:"fishes"`
+	SeaCreatures []Critter `json:"sea_creatures"`
+}
+
+// critters flattens the data into one list, with categories and hour maps
+// filled in.
+func (d critterData) critters() []Critter {
+	var all []Critter
+	for _, category := range []struct {
+		name     string
+		critters []Critter
+	}{{"bugs", d.Bugs}, {"fishes", d.Fishes}, {"sea_creatures", d.SeaCreatures}} {
+		for _, c := range category.critters {
+			c.Category = category.name
+			c.HourMap = season.HourMap(c.Hours)
+			if c.Category == "sea_creatures" && c.Location == "" {
+				c.Location = seaCreatureLocation
+			}
+			all = append(all, c)
+		}
+	}
+	return all
+}
+
+// Source is where acnhctl gets critters and keeps track of donations, which
+// are by critter ID.
+type Source interface {
+	Critters() ([]Critter, error)
+	Donated() (map[string][]string, error)
+	SetDonated(category, id string, donated bool) error
+}
+
+// Row is one critter in a listing, with its timing at the time asked about.
+// Leaves is when it goes if it's out then and ever does.
+type Row struct {
+	Category string        `json:"category"`
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Price    int           `json:"price"`
+	Location string        `json:"location"`
+	Timing   season.Timing `json:"timing"`
+	Hours    string        `json:"hours"`
+	Leaves   *time.Time    `json:"leaves,omitempty"`
+	Donated  bool          `json:"donated"`
+}
+
+func (r Row) When() string {
+	switch {
+	case r.Timing.AvailableAllDay:
+		return "all day"
+	case r.Timing.AvailableNow:
+		return "until " + r.Timing.DisplayUntil()
+	case r.Timing.Status == season.StatusOutOfSeason:
+		return "from " + r.Timing.DisplayNextMonth()
+	}
+	return r.Hours
+}
+
+// Info is everything about one critter, for the hemisphere and time asked
+// about.  Seasons has the months for both hemispheres.
+type Info struct {
+	Critter
+	Hemisphere season.Hemisphere            `json:"hemisphere"`
+	Seasons    map[season.Hemisphere]string `json:"seasons"`
+	HoursText  string                       `json:"hours_display"`
+	Timing     season.Timing                `json:"timing"`
+	Next       *season.Window               `json:"next,omitempty"`
+	Donated    bool                         `json:"donated"`
+}
+
+// inHemisphere returns the critter with its months moved to h.
+func inHemisphere(c Critter, h season.Hemisphere) Critter {
+	c.Months = season.Shift(c.Months, h)
+	return c
+}
+
+// donatedSet is the donations as category/ID keys.
+func donatedSet(donated map[string][]string) map[string]bool {
+	set := make(map[string]bool)
+	for category, ids := range donated {
+		for _, id := range ids {
+			set[category+"/"+id] = true
+		}
+	}
+	return set
+}
+
+func newRow(c Critter, t time.Time, donated map[string]bool) Row {
+	r := Row{
+		Category: c.Category,
+		ID:       c.ID,
+		Name:     c.Name,
+		Price:    c.Price,
+		Location: c.Location,
+		Timing:   season.At(c.Months, c.HourMap, t),
+		Hours:    season.DescribeHours(c.HourMap),
+		Donated:  donated[c.Category+"/"+c.ID],
+	}
+	if r.Timing.AvailableNow {
+		if w := season.Next(c.Months, c.HourMap, t); w != nil {
+			r.Leaves = w.End
+		}
+	}
+	return r
+}
+
+// outAt lists the critters around during t's hour, most valuable first.
+func outAt(critters []Critter, h season.Hemisphere, t time.Time, donated map[string]bool) []Row {
+	rows := []Row{}
+	for _, c := range critters {
+		c = inHemisphere(c, h)
+		if season.Around(c.Months, c.HourMap, t) {
+			rows = append(rows, newRow(c, t, donated))
+		}
+	}
+	sortRows(rows)
+	return rows
+}
+
+// leaving lists the critters around in t's month that won't be back the
+// month after, most valuable first.
+func leaving(critters []Critter, h season.Hemisphere, t time.Time, donated map[string]bool) []Row {
+	rows := []Row{}
+	for _, c := range critters {
+		c = inHemisphere(c, h)
+		if season.Leaving(c.Months, int(t.Month())-1) {
+			rows = append(rows, newRow(c, t, donated))
+		}
+	}
+	sortRows(rows)
+	return rows
+}
+
+func sortRows(rows []Row) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Price != rows[j].Price {
+			return rows[i].Price > rows[j].Price
+		}
+		return rows[i].Name < rows[j].Name
+	})
+}
+
+// find looks a critter up by name, ignoring case.  An exact match wins;
+// otherwise the name has to be part of exactly one critter's.
+func find(critters []Critter, name string) (Critter, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	var partial []Critter
+	for _, c := range critters {
+		lower := strings.ToLower(c.Name)
+		if lower == name {
+			return c, nil
+		}
+		if strings.Contains(lower, name) {
+			partial = append(partial, c)
+		}
+	}
+	switch len(partial) {
+	case 0:
+		return Critter{}, fmt.Errorf("no critter called %q", name)
+	case 1:
+		return partial[0], nil
+	}
+	var names []string
+	for _, c := range partial {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return Critter{}, fmt.Errorf("%q could be any of %s", name, strings.Join(names, ", "))
+}
+
+func info(c Critter, h season.Hemisphere, t time.Time, donated map[string]bool) Info {
+	i := Info{
+		Critter:    inHemisphere(c, h),
+		Hemisphere: h,
+		Seasons:    make(map[season.Hemisphere]string),
+		HoursText:  season.DescribeHours(c.HourMap),
+		Donated:    donated[c.Category+"/"+c.ID],
+	}
+	for _, hemisphere := range season.Hemispheres {
+		i.Seasons[hemisphere] = season.DescribeMonths(season.Shift(c.Months, hemisphere))
+	}
+	i.Timing = season.At(i.Months, i.HourMap, t)
+	i.Next = season.Next(i.Months, i.HourMap, t)
+	return i
+}
+
+// timeFormats are what "at" understands, tried in order.  Ones without a
+// date are for today.
+var timeFormats = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02", "15:04", "3PM", "3pm"}
+
+// parseTime reads a time on the island's clock, where now is.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	for _, layout := range timeFormats {
+		t, err := time.ParseInLocation(layout, s, now.Location())
+		if err != nil {
+			continue
+		}
+		if t.Year() == 0 {
+			t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		}
+		return t.In(now.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("can't read %q as a time; try \"2020-05-04 21:00\" or \"21:00\"", s)
+}
+
+func writeRows(w io.Writer, rows []Row, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(rows)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCATEGORY\tPRICE\tLOCATION\tWHEN\tDONATED")
+	for _, r := range rows {
+		donated := "no"
+		if r.Donated {
+			donated = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\n", r.Name, r.Category, r.Price, r.Location, r.When(), donated)
+	}
+	return tw.Flush()
+}
+
+func writeInfo(w io.Writer, i Info, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(i)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s (%s)\n", i.Name, i.Category)
+	fmt.Fprintf(tw, "Price:\t%d\n", i.Price)
+	fmt.Fprintf(tw, "Location:\t%s\n", i.Location)
+	if i.ShadowSize != "" {
+		fmt.Fprintf(tw, "Shadow:\t%s\n", i.ShadowSize)
+	}
+	if i.Speed != "" {
+		fmt.Fprintf(tw, "Speed:\t%s\n", i.Speed)
+	}
+	if i.Weather != "" {
+		fmt.Fprintf(tw, "Weather:\t%s\n", i.Weather)
+	}
+	if i.Requirement != "" {
+		fmt.Fprintf(tw, "Needs:\t%s\n", i.Requirement)
+	}
+	for _, h := range season.Hemispheres {
+		fmt.Fprintf(tw, "%s months:\t%s\n", h.Name(), i.Seasons[h])
+	}
+	fmt.Fprintf(tw, "Hours:\t%s\n", i.HoursText)
+	next := "not in the next year"
+	if i.Next != nil {
+		next = i.Next.Display()
+	}
+	fmt.Fprintf(tw, "Next (%s):\t%s\n", i.Hemisphere.Name(), next)
+	fmt.Fprintf(tw, "Donated:\t%t\n", i.Donated)
+	return tw.Flush()
+}
+
+// run does one command, writing what it finds to out.
+func run(src Source, args []string, h season.Hemisphere, now time.Time, asJSON bool, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("no command given")
+	}
+	command, arg := args[0], strings.Join(args[1:], " ")
+	if command == "at" || command == "info" || command == "donate" {
+		if arg == "" {
+			return fmt.Errorf("%s needs an argument", command)
+		}
+	} else if arg != "" {
+		return fmt.Errorf("%s doesn't take arguments", command)
+	}
+
+	critters, err := src.Critters()
+	if err != nil {
+		return err
+	}
+	switch command {
+	case "now", "at", "leaving", "info":
+		d, err := src.Donated()
+		if err != nil {
+			return err
+		}
+		donated := donatedSet(d)
+		switch command {
+		case "now":
+			return writeRows(out, outAt(critters, h, now, donated), asJSON)
+		case "at":
+			t, err := parseTime(arg, now)
+			if err != nil {
+				return err
+			}
+			return writeRows(out, outAt(critters, h, t, donated), asJSON)
+		case "leaving":
+			return writeRows(out, leaving(critters, h, now, donated), asJSON)
+		}
+		c, err := find(critters, arg)
+		if err != nil {
+			return err
+		}
+		return writeInfo(out, info(c, h, now, donated), asJSON)
+	case "donate":
+		c, err := find(critters, arg)
+		if err != nil {
+			return err
+		}
+		if err := src.SetDonated(c.Category, c.ID, true); err != nil {
+			return err
+		}
+		if asJSON {
+			return json.NewEncoder(out).Encode(c)
+		}
+		fmt.Fprintf(out, "Donated %s\n", c.Name)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q", command)
+}
+
+func defaultDonations() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".acnhctl.json"
+	}
+	return filepath.Join(home, ".acnhctl.json")
+}
+
+func main() {
+	flags := flag.NewFlagSet("acnhctl", flag.ExitOnError)
+	data := flags.String("data", "acnh.json", "critter data to read")
+	donations := flags.String("donations", defaultDonations(), "where to keep donations when not using a server")
+	server := flags.String("server", "", "acnh server to ask instead of reading -data, like http://localhost")
+	token := flags.String("token", "", "a token from the server's account page, for donating as your account")
+	user := flags.String("user", "", "your acnh_user cookie on the server, for donating without an account; find it in your browser's developer tools.  With an account, use -token instead")
+	hemisphere := flags.String("hemisphere", string(season.Northern), "north or south")
+	tz := flags.String("tz", "Local", "your island's time zone, like America/Los_Angeles")
+	asJSON := flags.Bool("json", false, "write JSON instead of a table")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	h, err := season.ParseHemisphere(*hemisphere)
+	if err != nil {
+		fail(err)
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		fail(err)
+	}
+	var src Source = &localSource{data: *data, donations: *donations}
+	if *server != "" {
+		src = newServerSource(*server, *user, *token)
+	}
+	if flags.Arg(0) == "tui" {
+		err = runTUI(src, h, loc)
+	} else {
+		err = run(src, flags.Args(), h, time.Now().In(loc), *asJSON, os.Stdout)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "acnhctl:", err)
+	os.Exit(1)
+}
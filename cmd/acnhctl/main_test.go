@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+const testData = `{
+	"bugs": [{"id": "tarantula", "name": "Tarantula", "price": 8000, "location": "On the ground", "months": [0, 1, 2, 3, 10, 11], "hours": [19, 20, 21, 22, 23, 0, 1, 2, 3]}],
+	"fishes": [{"id": "sea-bass", "name": "Sea Bass", "price": 400, "location": "Sea", "months": [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11], "hours": [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23]},
+	           {"id": "black-bass", "name": "Black Bass", "price": 400, "location": "River", "months": [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11], "hours": [9, 10, 11]}],
+	"sea_creatures": [{"id": "octopus", "name": "Octopus", "aliases": ["Octopuss"], "price": 1200, "months": [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11], "hours": [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23]}]
+}`
+
+func localTestSource(t *testing.T) (*localSource, func()) {
+	dir, err := ioutil.TempDir("", "acnhctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := filepath.Join(dir, "acnh.json")
+	if err := ioutil.WriteFile(data, []byte(testData), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return &localSource{data: data, donations: filepath.Join(dir, "donations.json")}, func() { os.RemoveAll(dir) }
+}
+
+func TestRun(t *testing.T) {
+	src, cleanup := localTestSource(t)
+	defer cleanup()
+	april := time.Date(2020, time.April, 4, 21, 30, 0, 0, time.UTC)
+	rows := func(args ...string) []Row {
+		var out bytes.Buffer
+		if err := run(src, args, season.Northern, april, true, &out); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		var rows []Row
+		if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+	names := func(rows []Row) string {
+		var n []string
+		for _, r := range rows {
+			n = append(n, r.Name)
+		}
+		return strings.Join(n, ",")
+	}
+
+	if got := names(rows("now")); got != "Tarantula,Octopus,Sea Bass" {
+		t.Errorf("expected night critters most valuable first, got %s", got)
+	}
+	if got := names(rows("at", "10:00")); got != "Octopus,Black Bass,Sea Bass" {
+		t.Errorf("expected morning critters, got %s", got)
+	}
+	if got := names(rows("leaving")); got != "Tarantula" {
+		t.Errorf("expected the tarantula to be leaving after April, got %s", got)
+	}
+	if got := rows("now")[1]; got.Location != seaCreatureLocation {
+		t.Errorf("expected sea creatures to be in the sea, got %+v", got)
+	}
+
+	var out bytes.Buffer
+	if err := run(src, []string{"donate", "tarantula"}, season.Northern, april, false, &out); err != nil || out.String() != "Donated Tarantula\n" {
+		t.Errorf("expected to donate the tarantula, got %q: %v", out.String(), err)
+	}
+	if r := rows("now"); !r[0].Donated || r[1].Donated {
+		t.Errorf("expected only the tarantula to be donated, got %+v", r)
+	}
+	if err := run(src, []string{"info", "bass"}, season.Northern, april, false, &out); err == nil || !strings.Contains(err.Error(), "Black Bass, Sea Bass") {
+		t.Errorf("expected an ambiguous name to list the choices, got %v", err)
+	}
+	if err := run(src, []string{"now", "please"}, season.Northern, april, false, &out); err == nil {
+		t.Errorf("expected now not to take arguments")
+	}
+}
+
+func TestLegacyDonations(t *testing.T) {
+	src, cleanup := localTestSource(t)
+	defer cleanup()
+	if err := ioutil.WriteFile(src.donations, []byte(`{"bugs": ["Tarantula"], "sea_creatures": ["Octopuss"]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := src.Donated(); err != nil || d["bugs"][0] != "tarantula" || d["sea_creatures"][0] != "octopus" {
+		t.Errorf("expected donations by name, old or new, to come back by ID, got %v: %v", d, err)
+	}
+	if err := src.SetDonated("fishes", "sea-bass", true); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(src.donations); strings.Contains(string(b), "Tarantula") || !strings.Contains(string(b), "tarantula") {
+		t.Errorf("expected the file to be moved over to IDs, got %s", b)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	src, cleanup := localTestSource(t)
+	defer cleanup()
+	critters, err := src.Critters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := find(critters, "Tarantula")
+	if err != nil {
+		t.Fatal(err)
+	}
+	may := time.Date(2020, time.May, 4, 12, 0, 0, 0, time.UTC)
+
+	i := info(c, season.Southern, may, nil)
+	if i.Seasons[season.Northern] != "November to April" || i.Seasons[season.Southern] != "May to October" {
+		t.Errorf("expected seasons for both hemispheres, got %v", i.Seasons)
+	}
+	if i.Timing.Status != season.StatusLaterToday || !i.Next.Start.Equal(time.Date(2020, time.May, 4, 19, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the tarantula out tonight in the south, got %+v %+v", i.Timing, i.Next)
+	}
+	if i = info(c, season.Northern, may, nil); i.Next.Start.Month() != time.November {
+		t.Errorf("expected the tarantula back in November in the north, got %+v", i.Next)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	now := time.Date(2020, time.May, 4, 12, 0, 0, 0, loc)
+	tests := []struct {
+		In   string
+		Want time.Time
+	}{
+		{"21:30", time.Date(2020, time.May, 4, 21, 30, 0, 0, loc)},
+		{"9PM", time.Date(2020, time.May, 4, 21, 0, 0, 0, loc)},
+		{"2020-07-01 05:00", time.Date(2020, time.July, 1, 5, 0, 0, 0, loc)},
+		{"2020-07-01T12:00:00Z", time.Date(2020, time.July, 1, 8, 0, 0, 0, loc)},
+	}
+	for _, test := range tests {
+		got, err := parseTime(test.In, now)
+		if err != nil || !got.Equal(test.Want) || got.Location() != loc {
+			t.Errorf("parseTime(%q) = %v, %v; want %v", test.In, got, err, test.Want)
+		}
+	}
+	if _, err := parseTime("teatime", now); err == nil {
+		t.Errorf("expected an error for a time that isn't one")
+	}
+}
+
+func TestServerSource(t *testing.T) {
+	donated := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("acnh_user"); err != nil || c.Value != "me" {
+			t.Errorf("expected the user's cookie, got %v", c)
+		}
+		switch r.URL.Path {
+		case "/api/critters":
+			if r.URL.Query().Get("all") != "true" || r.URL.Query().Get("hemisphere") != "north" {
+				t.Errorf("expected every northern critter to be asked for, got %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(testData))
+		case "/api/donations":
+			if r.Method == http.MethodPost {
+				donated[r.FormValue("category")] = append(donated[r.FormValue("category")], r.FormValue("id"))
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			json.NewEncoder(w).Encode(donated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	src := newServerSource(server.URL+"/", "me", "")
+	var out bytes.Buffer
+	if err := run(src, []string{"donate", "octopus"}, season.Northern, time.Now(), false, &out); err != nil {
+		t.Fatal(err)
+	}
+	d, err := src.Donated()
+	if err != nil || len(d["sea_creatures"]) != 1 || d["sea_creatures"][0] != "octopus" {
+		t.Errorf("expected the octopus to be donated on the server, got %v: %v", d, err)
+	}
+	if err := newServerSource(server.URL, "", "").SetDonated("bugs", "tarantula", true); err == nil {
+		t.Errorf("expected donating without a user to fail")
+	}
+}
+
+func TestServerSourceToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("acnh_user"); err == nil {
+			t.Errorf("expected no acnh_user cookie alongside a token")
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "that token is no good", http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := newServerSource(server.URL, "", "secret").SetDonated("bugs", "tarantula", true); err != nil {
+		t.Errorf("expected donating with a token to work, got %v", err)
+	}
+	if err := newServerSource(server.URL, "", "stale").SetDonated("bugs", "tarantula", true); err == nil {
+		t.Errorf("expected donating with a token the server won't take to fail")
+	}
+}
+
+func TestServerSourceRejectedUser(t *testing.T) {
+	// The server hands out a new acnh_user when the one it's given belongs
+	// to an account.
+	posted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "acnh_user", Value: "someone new"})
+		if r.Method == http.MethodPost {
+			posted = true
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	src := newServerSource(server.URL, "registered", "")
+	if _, err := src.Donated(); err == nil {
+		t.Errorf("expected donations for a rejected user to fail")
+	}
+	if err := src.SetDonated("bugs", "tarantula", true); err == nil || !posted {
+		t.Errorf("expected donating as a rejected user to fail, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localSource reads critters out of acnh.json and keeps donations in a file
+// of its own, in the same shape as the server's /api/donations.  Files from
+// before critters had IDs have names in them instead, which get moved over
+// to IDs the next time something's donated.
+type localSource struct {
+	data      string
+	donations string
+}
+
+func (s *localSource) Critters() ([]Critter, error) {
+	file, err := os.Open(s.data)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var d critterData
+	if err := json.NewDecoder(file).Decode(&d); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", s.data, err)
+	}
+	return d.critters(), nil
+}
+
+func (s *localSource) Donated() (map[string][]string, error) {
+	donated := make(map[string][]string)
+	b, err := ioutil.ReadFile(s.donations)
+	if os.IsNotExist(err) {
+		return donated, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &donated); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", s.donations, err)
+	}
+	critters, err := s.Critters()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string)
+	for _, c := range critters {
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			ids[c.Category+"/"+name] = c.ID
+		}
+	}
+	for category, keys := range donated {
+		for i, key := range keys {
+			if id, ok := ids[category+"/"+key]; ok {
+				keys[i] = id
+			}
+		}
+	}
+	return donated, nil
+}
+
+func (s *localSource) SetDonated(category, id string, donated bool) error {
+	all, err := s.Donated()
+	if err != nil {
+		return err
+	}
+	ids := []string{}
+	for _, d := range all[category] {
+		if d != id {
+			ids = append(ids, d)
+		}
+	}
+	if donated {
+		ids = append(ids, id)
+	}
+	all[category] = ids
+	b, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.donations + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.donations)
+}
+
+// serverSource asks an acnh server, on behalf of either an account or an
+// anonymous user; without either, each request is somebody new.  An account
+// is the token from the server's account page, sent as a bearer token.  An
+// anonymous user is the acnh_user cookie the server gave the browser whose
+// donations these are.  Once that cookie belongs to an account the server
+// hands out a new one, which do turns into an error rather than quietly
+// using it.
+type serverSource struct {
+	base   string
+	user   string
+	token  string
+	client *http.Client
+}
+
+func newServerSource(base, user, token string) *serverSource {
+	return &serverSource{
+		base:   strings.TrimSuffix(base, "/"),
+		user:   user,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// anonymous is whether requests are from nobody in particular.
+func (s *serverSource) anonymous() bool {
+	return s.user == "" && s.token == ""
+}
+
+func (s *serverSource) do(method, path string, form url.Values, v interface{}) error {
+	req, err := http.NewRequest(method, s.base+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	} else if s.user != "" {
+		req.AddCookie(&http.Cookie{Name: "acnh_user", Value: s.user})
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	for _, c := range resp.Cookies() {
+		if !s.anonymous() && c.Name == "acnh_user" && c.Value != s.user {
+			return fmt.Errorf("the server won't take %q as -user; if you've registered an account, make a token on its account page and use -token instead", s.user)
+		}
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: %s %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Critters asks for every critter in the northern hemisphere, in or out of
+// season, so it can work out timings the same way acnh.json gets used.
+func (s *serverSource) Critters() ([]Critter, error) {
+	var d critterData
+	if err := s.do(http.MethodGet, "/api/critters?all=true&hemisphere=north", nil, &d); err != nil {
+		return nil, err
+	}
+	return d.critters(), nil
+}
+
+func (s *serverSource) Donated() (map[string][]string, error) {
+	donated := make(map[string][]string)
+	if s.anonymous() {
+		return donated, nil
+	}
+	return donated, s.do(http.MethodGet, "/api/donations", nil, &donated)
+}
+
+func (s *serverSource) SetDonated(category, id string, donated bool) error {
+	if s.anonymous() {
+		return errors.New("donating on a server needs -token or -user")
+	}
+	return s.do(http.MethodPost, "/api/donations", url.Values{"category": {category}, "id": {id}, "donated": {strconv.FormatBool(donated)}}, nil)
+}
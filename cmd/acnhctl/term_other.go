@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+var errNoTerminal = errors.New("the full-screen mode isn't supported here")
+
+func makeRaw(fd int) (func() error, error) {
+	return nil, errNoTerminal
+}
+
+func termSize(fd int) (int, int, error) {
+	return 0, 0, errNoTerminal
+}
+
+func notifyResize(c chan<- os.Signal) {}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+// Keys that aren't printable runes.  Printable ones are themselves.
+const (
+	keyUp rune = -(iota + 1)
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyEscape
+	keyBackspace
+	keyTab
+)
+
+// parseKeys turns what the terminal sent into keys.  Escape sequences it
+// doesn't know are dropped.
+func parseKeys(b []byte) []rune {
+	var keys []rune
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			if len(b) == 1 {
+				keys = append(keys, keyEscape)
+				break
+			}
+			if b[1] == '[' || b[1] == 'O' {
+				n := 2
+				for n < len(b) && (b[n] < 0x40 || b[n] > 0x7e) {
+					n++
+				}
+				if n < len(b) {
+					switch string(b[2 : n+1]) {
+					case "A":
+						keys = append(keys, keyUp)
+					case "B":
+						keys = append(keys, keyDown)
+					case "5~":
+						keys = append(keys, keyPageUp)
+					case "6~":
+						keys = append(keys, keyPageDown)
+					case "H", "1~":
+						keys = append(keys, keyHome)
+					case "F", "4~":
+						keys = append(keys, keyEnd)
+					}
+					n++
+				}
+				b = b[n:]
+				continue
+			}
+			keys = append(keys, keyEscape)
+			b = b[1:]
+			continue
+		}
+		r, size := utf8.DecodeRune(b)
+		b = b[size:]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, keyEnter)
+		case 0x7f, 0x08:
+			keys = append(keys, keyBackspace)
+		case '\t':
+			keys = append(keys, keyTab)
+		default:
+			keys = append(keys, r)
+		}
+	}
+	return keys
+}
+
+// tuiCategories are what tab cycles through, everything first.
+var tuiCategories = []struct {
+	id, name string
+}{{"", "All"}, {"bugs", "Bugs"}, {"fishes", "Fish"}, {"sea_creatures", "Sea creatures"}}
+
+// tui is the full-screen view of what's out now: the critters, which of
+// them are donated, and what the keyboard has done to the view.
+type tui struct {
+	src        Source
+	critters   []Critter
+	hemisphere season.Hemisphere
+	donated    map[string]bool
+
+	category    int
+	filter      string
+	editing     bool
+	hideDonated bool
+	cursor      int
+	top         int
+	message     string
+}
+
+// rows are the critters shown at t: out now, in the chosen category,
+// matching the filter, grouped by category and most valuable first.
+func (v *tui) rows(t time.Time) []Row {
+	order := map[string]int{"bugs": 0, "fishes": 1, "sea_creatures": 2}
+	filter := strings.ToLower(v.filter)
+	var rows []Row
+	for _, r := range outAt(v.critters, v.hemisphere, t, v.donated) {
+		if id := tuiCategories[v.category].id; id != "" && r.Category != id {
+			continue
+		}
+		if v.hideDonated && r.Donated {
+			continue
+		}
+		if filter != "" && !strings.Contains(strings.ToLower(r.Name), filter) && !strings.Contains(strings.ToLower(r.Location), filter) {
+			continue
+		}
+		rows = append(rows, r)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return order[rows[i].Category] < order[rows[j].Category]
+	})
+	return rows
+}
+
+// handle does what a key asks, given the rows on screen, and says whether
+// it's time to quit.
+func (v *tui) handle(key rune, rows []Row, page int) bool {
+	v.message = ""
+	if v.editing {
+		switch key {
+		case keyEnter, keyEscape:
+			v.editing = false
+		case keyBackspace:
+			if _, size := utf8.DecodeLastRuneInString(v.filter); size > 0 {
+				v.filter = v.filter[:len(v.filter)-size]
+			}
+		default:
+			if key > 0 {
+				v.filter += string(key)
+			}
+		}
+		v.cursor = 0
+		return false
+	}
+	switch key {
+	case 'q', 0x03:
+		return true
+	case keyUp, 'k':
+		v.cursor--
+	case keyDown, 'j':
+		v.cursor++
+	case keyPageUp:
+		v.cursor -= page
+	case keyPageDown:
+		v.cursor += page
+	case keyHome, 'g':
+		v.cursor = 0
+	case keyEnd, 'G':
+		v.cursor = len(rows) - 1
+	case keyTab:
+		v.category = (v.category + 1) % len(tuiCategories)
+		v.cursor = 0
+	case '/':
+		v.editing = true
+	case keyEscape:
+		v.filter = ""
+	case 'h':
+		v.hideDonated = !v.hideDonated
+	case ' ', 'd':
+		if v.cursor >= 0 && v.cursor < len(rows) {
+			v.toggle(rows[v.cursor])
+		}
+	}
+	return false
+}
+
+// toggle flips whether r is donated, and keeps track of it in the source.
+func (v *tui) toggle(r Row) {
+	key := r.Category + "/" + r.ID
+	if err := v.src.SetDonated(r.Category, r.ID, !v.donated[key]); err != nil {
+		v.message = err.Error()
+		return
+	}
+	v.donated[key] = !v.donated[key]
+	if v.donated[key] {
+		v.message = "Donated " + r.Name
+	} else {
+		v.message = "Took back " + r.Name
+	}
+}
+
+// countdown is how long until leaves, like "2:05:09" or "3d 2:05:09".
+func countdown(leaves *time.Time, t time.Time) string {
+	if leaves == nil {
+		return "always"
+	}
+	d := leaves.Sub(t).Truncate(time.Second)
+	if d < 0 {
+		d = 0
+	}
+	s := fmt.Sprintf("%d:%02d:%02d", int(d.Hours())%24, int(d.Minutes())%60, int(d.Seconds())%60)
+	if days := int(d.Hours()) / 24; days > 0 {
+		s = fmt.Sprintf("%dd %s", days, s)
+	}
+	return s
+}
+
+// fit pads or cuts s to exactly n characters.
+func fit(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	r := []rune(s)
+	if len(r) > n {
+		if n == 1 {
+			return "…"
+		}
+		return string(r[:n-1]) + "…"
+	}
+	return s + strings.Repeat(" ", n-len(r))
+}
+
+// render draws the screen for t into width by height characters.
+func (v *tui) render(w io.Writer, t time.Time, width, height int) {
+	rows := v.rows(t)
+	if v.cursor >= len(rows) {
+		v.cursor = len(rows) - 1
+	}
+	if v.cursor < 0 {
+		v.cursor = 0
+	}
+
+	// Everything on screen, as lines: headings for each category as it
+	// starts, then its critters.  at is the line the cursor's on.
+	type line struct {
+		text     string
+		selected bool
+		heading  bool
+	}
+	nameWidth := width - len("[ ]  1234567    00d 00:00:00")
+	locWidth := nameWidth / 2
+	nameWidth -= locWidth
+	columns := func(mark, name, price, location, leaves string) string {
+		return fmt.Sprintf("%s %s %7s %s %14s", mark, fit(name, nameWidth), price, fit(location, locWidth), leaves)
+	}
+
+	var lines []line
+	at := 0
+	for i, r := range rows {
+		if i == 0 || r.Category != rows[i-1].Category {
+			for _, c := range tuiCategories {
+				if c.id == r.Category {
+					lines = append(lines, line{text: c.name, heading: true})
+				}
+			}
+		}
+		mark := "[ ]"
+		if r.Donated {
+			mark = "[x]"
+		}
+		if i == v.cursor {
+			at = len(lines)
+		}
+		lines = append(lines, line{
+			text:     columns(mark, r.Name, fmt.Sprint(r.Price), r.Location, countdown(r.Leaves, t)),
+			selected: i == v.cursor,
+		})
+	}
+
+	// Scroll so the cursor's on screen, along with its heading if that's
+	// just above it.
+	body := height - 3
+	if body < 1 {
+		body = 1
+	}
+	first := at
+	if at > 0 && lines[at-1].heading {
+		first = at - 1
+	}
+	if first < v.top {
+		v.top = first
+	}
+	if at >= v.top+body {
+		v.top = at - body + 1
+	}
+	if v.top > len(lines)-body {
+		v.top = len(lines) - body
+	}
+	if v.top < 0 {
+		v.top = 0
+	}
+
+	title := fmt.Sprintf("Out now, %s, %s hemisphere — %s", t.Format("Mon Jan 2 3:04:05PM"), v.hemisphere.Name(), tuiCategories[v.category].name)
+	if v.filter != "" || v.editing {
+		title += " matching \"" + v.filter + "\""
+	}
+	fmt.Fprint(w, "\x1b[H\x1b[1m"+fit(title, width)+"\x1b[0m\x1b[K\r\n")
+	fmt.Fprint(w, fit(columns("   ", "Name", "Price", "Location", "Leaves in"), width)+"\x1b[K\r\n")
+	for i := v.top; i < v.top+body; i++ {
+		switch {
+		case i >= len(lines):
+			if i == 0 {
+				fmt.Fprint(w, "Nothing out right now.")
+			}
+		case lines[i].heading:
+			fmt.Fprint(w, "\x1b[4m"+fit(lines[i].text, width)+"\x1b[0m")
+		case lines[i].selected:
+			fmt.Fprint(w, "\x1b[7m"+fit(lines[i].text, width)+"\x1b[0m")
+		default:
+			fmt.Fprint(w, fit(lines[i].text, width))
+		}
+		fmt.Fprint(w, "\x1b[K\r\n")
+	}
+	footer := "↑↓ move  space donate  tab category  / filter  esc clear  h hide donated  q quit"
+	switch {
+	case v.editing:
+		footer = "Filter: " + v.filter + "_"
+	case v.message != "":
+		footer = v.message
+	}
+	fmt.Fprint(w, fit(footer, width)+"\x1b[K\x1b[J")
+}
+
+// runTUI takes over the terminal until q is pressed.  The view is redrawn
+// every second, so countdowns tick and critters come and go on the hour.
+func runTUI(src Source, h season.Hemisphere, loc *time.Location) error {
+	critters, err := src.Critters()
+	if err != nil {
+		return err
+	}
+	d, err := src.Donated()
+	if err != nil {
+		return err
+	}
+	v := &tui{src: src, critters: critters, hemisphere: h, donated: donatedSet(d)}
+
+	fd := int(os.Stdin.Fd())
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	out := bufio.NewWriter(os.Stdout)
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+		out.Flush()
+	}()
+
+	keys := make(chan []rune)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- parseKeys(buf[:n])
+		}
+	}()
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	for {
+		width, height, err := termSize(fd)
+		if err != nil || width == 0 {
+			width, height = 80, 24
+		}
+		now := time.Now().In(loc)
+		v.render(out, now, width, height)
+		out.Flush()
+
+		select {
+		case ks, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range ks {
+				if v.handle(k, v.rows(now), height-4) {
+					return nil
+				}
+			}
+		case <-resized:
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/swerveaux/acnh/season"
+)
+
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("j\x1b[A\x1b[6~ \r\x7f\x1b\x1b[99Xé\t"))
+	want := []rune{'j', keyUp, keyPageDown, ' ', keyEnter, keyBackspace, keyEscape, 'é', keyTab}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseKeys = %v, want %v", got, want)
+	}
+}
+
+func TestTUI(t *testing.T) {
+	src, cleanup := localTestSource(t)
+	defer cleanup()
+	critters, err := src.Critters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &tui{src: src, critters: critters, hemisphere: season.Northern, donated: map[string]bool{}}
+	night := time.Date(2020, time.April, 4, 21, 30, 0, 0, time.UTC)
+	press := func(keys string) {
+		for _, k := range parseKeys([]byte(keys)) {
+			v.handle(k, v.rows(night), 10)
+		}
+	}
+	names := func() string {
+		var n []string
+		for _, r := range v.rows(night) {
+			n = append(n, r.Name)
+		}
+		return strings.Join(n, ",")
+	}
+
+	if got := names(); got != "Tarantula,Sea Bass,Octopus" {
+		t.Errorf("expected critters grouped by category, got %s", got)
+	}
+	press("j ")
+	if d, _ := src.Donated(); len(d["fishes"]) != 1 || d["fishes"][0] != "sea-bass" {
+		t.Errorf("expected the sea bass to be donated in the file, got %v", d)
+	}
+	press("h")
+	if got := names(); got != "Tarantula,Octopus" {
+		t.Errorf("expected donated critters to be hidden, got %s", got)
+	}
+	press("h/ground\r")
+	if got := names(); got != "Tarantula" || v.editing {
+		t.Errorf("expected to filter by location, got %s", got)
+	}
+	press("\x1b\t\t")
+	if got := names(); got != "Sea Bass" {
+		t.Errorf("expected tab to pick fish, got %s", got)
+	}
+	press(" ")
+	if d, _ := src.Donated(); len(d["fishes"]) != 0 {
+		t.Errorf("expected the sea bass to be taken back, got %v", d)
+	}
+
+	var b bytes.Buffer
+	v.render(&b, night, 60, 10)
+	if !strings.Contains(b.String(), "Fish") || !strings.Contains(b.String(), "[ ] Sea Bass") || !strings.Contains(b.String(), "always") {
+		t.Errorf("unexpected screen %q", b.String())
+	}
+}
+
+func TestCountdown(t *testing.T) {
+	now := time.Date(2020, time.April, 4, 21, 30, 15, 0, time.UTC)
+	soon := time.Date(2020, time.April, 5, 4, 0, 0, 0, time.UTC)
+	later := time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC)
+	if got := countdown(&soon, now); got != "6:29:45" {
+		t.Errorf("countdown = %q", got)
+	}
+	if got := countdown(&later, now); got != "26d 2:29:45" {
+		t.Errorf("countdown = %q", got)
+	}
+	if got := countdown(nil, now); got != "always" {
+		t.Errorf("countdown = %q", got)
+	}
+}
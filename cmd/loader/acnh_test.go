@@ -1,6 +1,9 @@
 package main
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestRng(t *testing.T) {
 	tests := []struct {
@@ -297,3 +300,318 @@ func areIntSlicesEqual(a, b []int) bool {
 
 	return true
 }
+
+func TestParseShadowSize(t *testing.T) {
+	tests := []struct {
+		Input       string
+		Expected    string
+		Fin         bool
+		ShouldError bool
+	}{
+		{"Small", "Small", false, false},
+		{"TIny", "Tiny", false, false},
+		{"XXL - with an extra fin", "XXL", true, false},
+		{"Large- with an extra fin", "Large", true, false},
+		{"Unique - Long/thin/narrow", "Narrow", false, false},
+		{"XL", "XL", false, false},
+		{"", "", false, true},
+		{"enormous", "", false, true},
+	}
+
+	for _, test := range tests {
+		got, fin, err := parseShadowSize(test.Input)
+		if test.ShouldError {
+			if err == nil {
+				t.Errorf("Failed test '%s', should have errored but didn't", test.Input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Failed test '%s', shouldn't have errored but got '%v'", test.Input, err)
+			continue
+		}
+		if got != test.Expected || fin != test.Fin {
+			t.Errorf("Failed test '%s': expected %s (fin %t), got %s (fin %t)", test.Input, test.Expected, test.Fin, got, fin)
+		}
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		Input       string
+		Expected    string
+		ShouldError bool
+	}{
+		{"Stationary", "Stationary", false},
+		{"very  SLOW", "Very slow", false},
+		{"Fast", "Fast", false},
+		{"warp speed", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := parseSpeed(test.Input)
+		if test.ShouldError && err == nil {
+			t.Errorf("Failed test '%s', should have errored but didn't", test.Input)
+		}
+		if !test.ShouldError && got != test.Expected {
+			t.Errorf("Failed test '%s': expected %s, got %s (err %v)", test.Input, test.Expected, got, err)
+		}
+	}
+}
+
+func TestParseYesNo(t *testing.T) {
+	tests := []struct {
+		Input       string
+		Expected    bool
+		ShouldError bool
+	}{
+		{"Yes", true, false},
+		{" no ", false, false},
+		{"", false, false},
+		{"maybe", false, true},
+	}
+
+	for _, test := range tests {
+		got, err := parseYesNo(test.Input)
+		if test.ShouldError && err == nil {
+			t.Errorf("Failed test '%s', should have errored but didn't", test.Input)
+		}
+		if !test.ShouldError && (err != nil || got != test.Expected) {
+			t.Errorf("Failed test '%s': expected %t, got %t (err %v)", test.Input, test.Expected, got, err)
+		}
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		Input       string
+		Expected    Price
+		ShouldError bool
+	}{
+		{"840", Price{Amount: 840}, false},
+		{"1,400", Price{Amount: 1400}, false},
+		{"NFS", Price{State: NotForSale}, false},
+		{"nfs", Price{State: NotForSale}, false},
+		{"NA", Price{State: NotApplicable}, false},
+		{"", Price{State: NotApplicable}, false},
+		{"lots", Price{}, true},
+	}
+
+	for _, test := range tests {
+		got, err := parsePrice(test.Input)
+		if test.ShouldError && err == nil {
+			t.Errorf("Failed test '%s', should have errored but didn't", test.Input)
+		}
+		if !test.ShouldError && (err != nil || got != test.Expected) {
+			t.Errorf("Failed test '%s': expected %v, got %v (err %v)", test.Input, test.Expected, got, err)
+		}
+	}
+}
+
+func TestParseBirthday(t *testing.T) {
+	tests := []struct {
+		Input       string
+		Month       int
+		Day         int
+		ShouldError bool
+	}{
+		{"March 10", 2, 10, false},
+		{"january 1", 0, 1, false},
+		{"Feb 29", 1, 29, false},
+		{"February 30", 0, 0, true},
+		{"Smarch 3", 0, 0, true},
+		{"October", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		month, day, err := parseBirthday(test.Input)
+		if test.ShouldError {
+			if err == nil {
+				t.Errorf("Failed test '%s', should have errored but didn't", test.Input)
+			}
+			continue
+		}
+		if err != nil || month != test.Month || day != test.Day {
+			t.Errorf("Failed test '%s': expected %d/%d, got %d/%d (err %v)", test.Input, test.Month, test.Day, month, day, err)
+		}
+	}
+}
+
+func TestParseIngredients(t *testing.T) {
+	tests := []struct {
+		Input       string
+		Expected    []Ingredient
+		ShouldError bool
+	}{
+		{"5 tree branch", []Ingredient{{"tree branch", 5}}, false},
+		{"1 flimsy axe; 3 wood; 1 iron nugget", []Ingredient{{"flimsy axe", 1}, {"wood", 3}, {"iron nugget", 1}}, false},
+		{"3 wood;", []Ingredient{{"wood", 3}}, false},
+		{"wood", nil, true},
+		{"some wood", nil, true},
+		{"0 wood", nil, true},
+		{"", nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := parseIngredients(test.Input)
+		if test.ShouldError {
+			if err == nil {
+				t.Errorf("Failed test '%s', should have errored but didn't", test.Input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Failed test '%s', shouldn't have errored but got '%v'", test.Input, err)
+			continue
+		}
+		if len(got) != len(test.Expected) {
+			t.Errorf("Failed test '%s': expected %v, got %v", test.Input, test.Expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.Expected[i] {
+				t.Errorf("Failed test '%s': expected %v, got %v", test.Input, test.Expected, got)
+				break
+			}
+		}
+	}
+}
+
+func TestParseWeather(t *testing.T) {
+	tests := []struct {
+		Input       string
+		Expected    string
+		ShouldError bool
+	}{
+		{"", "", false},
+		{"Any", "", false},
+		{"Rain", "Rain", false},
+		{"no  rain", "No rain", false},
+		{"snow", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := parseWeather(test.Input)
+		if test.ShouldError && err == nil {
+			t.Errorf("Failed test '%s', should have errored but didn't", test.Input)
+		}
+		if !test.ShouldError && (err != nil || got != test.Expected) {
+			t.Errorf("Failed test '%s': expected '%s', got '%s' (err %v)", test.Input, test.Expected, got, err)
+		}
+	}
+}
+
+func TestParseRarity(t *testing.T) {
+	tests := []struct {
+		Input       string
+		Expected    int
+		ShouldError bool
+	}{
+		{"", 0, false},
+		{"1", 1, false},
+		{"5", 5, false},
+		{"0", 0, true},
+		{"6", 0, true},
+		{"rare", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := parseRarity(test.Input)
+		if test.ShouldError && err == nil {
+			t.Errorf("Failed test '%s', should have errored but didn't", test.Input)
+		}
+		if !test.ShouldError && (err != nil || got != test.Expected) {
+			t.Errorf("Failed test '%s': expected %d, got %d (err %v)", test.Input, test.Expected, got, err)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"Ant", "ant"},
+		{"Rajah Brooke's Birdwing", "rajah-brookes-birdwing"},
+		{"Venus' flower basket", "venus-flower-basket"},
+		{"Man-faced Stink Bug", "man-faced-stink-bug"},
+		{"Mahi-mahi", "mahi-mahi"},
+		{" Sea  Bass ", "sea-bass"},
+	}
+
+	for _, test := range tests {
+		if got := slug(test.Input); got != test.Expected {
+			t.Errorf("Failed test '%s': expected '%s', got '%s'", test.Input, test.Expected, got)
+		}
+	}
+}
+
+func TestReadNames(t *testing.T) {
+	names, err := readNames(strings.NewReader("Category,Name,es,DE\nbugs,Ant,Hormiga,Ameise\nfishes,Koi,Carpa koi,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names["bugs"]["Ant"]["es"] != "Hormiga" || names["bugs"]["Ant"]["de"] != "Ameise" {
+		t.Errorf("expected the ant in both languages, got %v", names["bugs"]["Ant"])
+	}
+	if _, ok := names["fishes"]["Koi"]["de"]; ok {
+		t.Errorf("expected a blank translation to be left out, got %v", names["fishes"]["Koi"])
+	}
+
+	bugs := []Bug{{ID: "ant", Name: "Ant"}}
+	fishes := []Fish{{Bug: Bug{ID: "koi", Name: "Koi"}}}
+	if err := names.apply(bugs, fishes, nil); err != nil || bugs[0].Names["es"] != "Hormiga" {
+		t.Errorf("expected the ant's names to be filled in, got %v: %v", bugs[0].Names, err)
+	}
+	if err := names.apply(bugs, nil, nil); err == nil {
+		t.Errorf("expected a translation for a missing critter to be an error")
+	}
+}
+
+func TestReadAliases(t *testing.T) {
+	aliases, err := readAliases(strings.NewReader("Category,Old name,Name\nbugs,Jewel Bettle,Jewel Beetle\nfossils,Ankylo skul,Ankylo Skull\nfossils,Ankylo skull,Ankylo Skull\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bugs := []Bug{{ID: "ant", Name: "Ant"}, {ID: "jewel-beetle", Name: "Jewel Beetle"}}
+	fossils := []Fossil{{ID: "ankylo-skull", Name: "Ankylo Skull"}}
+	if err := aliases.apply(&ACNH{Bugs: bugs, Fossils: fossils}); err != nil {
+		t.Fatal(err)
+	}
+	if bugs[0].ID != "ant" || bugs[0].Aliases != nil {
+		t.Errorf("expected the ant to be left alone, got %+v", bugs[0])
+	}
+	if bugs[1].ID != "jewel-bettle" || len(bugs[1].Aliases) != 1 || bugs[1].Aliases[0] != "Jewel Bettle" {
+		t.Errorf("expected the jewel beetle to keep its old ID, got %+v", bugs[1])
+	}
+	if fossils[0].ID != "ankylo-skul" || len(fossils[0].Aliases) != 2 {
+		t.Errorf("expected the skull to keep its oldest ID and both old names, got %+v", fossils[0])
+	}
+
+	if err := aliases.apply(&ACNH{Bugs: bugs[:1], Fossils: fossils}); err == nil {
+		t.Errorf("expected an alias for a missing entry to be an error")
+	}
+	aliases, err = readAliases(strings.NewReader("Category,Old name,Name\nbugs,Ant,Jewel Beetle\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aliases.apply(&ACNH{Bugs: []Bug{{ID: "ant", Name: "Ant"}, {ID: "jewel-beetle", Name: "Jewel Beetle"}}}); err == nil {
+		t.Errorf("expected two entries with the same ID to be an error")
+	}
+
+	aliases, err = readAliases(strings.NewReader("Category,Old name,Name\nvillagers,Sherbet,Sherb\nrecipes,ice wand,frozen wand\numbrellas,Leaf umbrela,Leaf umbrella\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	acnh := ACNH{
+		Villagers: []Villager{{ID: "sherb", Name: "Sherb"}},
+		Recipes:   []Recipe{{ID: "frozen-wand", Name: "frozen wand"}},
+		Catalog:   []CatalogCategory{{ID: "umbrellas", Items: []CatalogItem{{ID: "leaf-umbrella", Name: "Leaf umbrella"}}}},
+	}
+	if err := aliases.apply(&acnh); err != nil {
+		t.Fatal(err)
+	}
+	if acnh.Villagers[0].ID != "sherbet" || acnh.Recipes[0].ID != "ice-wand" || acnh.Catalog[0].Items[0].ID != "leaf-umbrela" {
+		t.Errorf("expected villagers, recipes and catalog items to keep their old IDs, got %+v", acnh)
+	}
+}
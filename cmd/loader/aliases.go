@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Aliases are the names catalog entries used to go by: category, then the
+// entry's name now, then its old names, oldest first.  Categories are the
+// museum's, "villagers", "recipes" and the catalog categories' IDs.
+type Aliases map[string]map[string][]string
+
+// processAliases reads renamed entries from a CSV file with Category, Old
+// name and Name columns.  An entry renamed more than once gets a row for
+// each of its old names, oldest first.
+func processAliases(filename string) (Aliases, error) {
+	aliases := make(Aliases)
+	inFile, err := os.Open(filename)
+	if err != nil {
+		return aliases, fmt.Errorf("unable to open aliases CSV file: %w", err)
+	}
+	defer inFile.Close()
+	return readAliases(inFile)
+}
+
+func readAliases(in io.Reader) (Aliases, error) {
+	aliases := make(Aliases)
+	r := csv.NewReader(in)
+	// Read off header line
+	if _, err := r.Read(); err != nil {
+		return aliases, fmt.Errorf("somehow errored reading header line on aliases input file: %w", err)
+	}
+
+	for {
+		fields, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return aliases, fmt.Errorf("reading aliases input file: %w", err)
+		}
+		if len(fields) != 3 {
+			return aliases, fmt.Errorf("aliases row %v needs a category, an old name and a name", fields)
+		}
+		category, old, name := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1]), strings.TrimSpace(fields[2])
+		if aliases[category] == nil {
+			aliases[category] = make(map[string][]string)
+		}
+		aliases[category][name] = append(aliases[category][name], old)
+	}
+	return aliases, nil
+}
+
+// entry is what apply needs from anything with an ID.
+type entry struct {
+	name    string
+	id      *string
+	aliases *[]string
+}
+
+// apply fills in the entries' Aliases, and keeps each renamed entry's ID
+// what it was under its oldest name, so anything tracked by ID doesn't lose
+// track of it.  An alias for an entry that doesn't exist, or two entries
+// ending up with the same ID, is an error.
+func (a Aliases) apply(acnh *ACNH) error {
+	entries := make(map[string][]entry)
+	for i, b := range acnh.Bugs {
+		entries["bugs"] = append(entries["bugs"], entry{b.Name, &acnh.Bugs[i].ID, &acnh.Bugs[i].Aliases})
+	}
+	for i, f := range acnh.Fishes {
+		entries["fishes"] = append(entries["fishes"], entry{f.Name, &acnh.Fishes[i].ID, &acnh.Fishes[i].Aliases})
+	}
+	for i, s := range acnh.SeaCreatures {
+		entries["sea_creatures"] = append(entries["sea_creatures"], entry{s.Name, &acnh.SeaCreatures[i].ID, &acnh.SeaCreatures[i].Aliases})
+	}
+	for i, f := range acnh.Fossils {
+		entries["fossils"] = append(entries["fossils"], entry{f.Name, &acnh.Fossils[i].ID, &acnh.Fossils[i].Aliases})
+	}
+	for i, art := range acnh.Art {
+		entries["art"] = append(entries["art"], entry{art.Name, &acnh.Art[i].ID, &acnh.Art[i].Aliases})
+	}
+	for i, v := range acnh.Villagers {
+		entries["villagers"] = append(entries["villagers"], entry{v.Name, &acnh.Villagers[i].ID, &acnh.Villagers[i].Aliases})
+	}
+	for i, r := range acnh.Recipes {
+		entries["recipes"] = append(entries["recipes"], entry{r.Name, &acnh.Recipes[i].ID, &acnh.Recipes[i].Aliases})
+	}
+	for _, c := range acnh.Catalog {
+		for i, item := range c.Items {
+			entries[c.ID] = append(entries[c.ID], entry{item.Name, &c.Items[i].ID, &c.Items[i].Aliases})
+		}
+	}
+
+	for category, es := range entries {
+		used := make(map[string]bool)
+		ids := make(map[string]string)
+		for _, e := range es {
+			if old := a[category][e.name]; len(old) > 0 {
+				*e.id = slug(old[0])
+				*e.aliases = old
+				used[e.name] = true
+			}
+			if other, ok := ids[*e.id]; ok {
+				return fmt.Errorf("%s %q and %q both have the ID %q", category, other, e.name, *e.id)
+			}
+			ids[*e.id] = e.name
+		}
+		for name := range a[category] {
+			if !used[name] {
+				return fmt.Errorf("there's an alias for %s %q, but no such entry", category, name)
+			}
+		}
+	}
+	for category := range a {
+		if _, ok := entries[category]; !ok {
+			return fmt.Errorf("there are aliases for %s, which isn't in the museum", category)
+		}
+	}
+	return nil
+}
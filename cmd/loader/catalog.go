@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// catalogRegistry lists every catalog category and the CSV it's loaded from.
+// Adding a new category is just a matter of dropping in a CSV with (some of)
+// the usual columns and adding a line here.
+var catalogRegistry = []struct {
+	ID   string
+	Name string
+	File string
+}{
+	{"umbrellas", "Umbrellas", "umbrellas.csv"},
+	{"wallpapers", "Wallpapers", "wallpapers.csv"},
+	{"rugs", "Rugs", "rugs.csv"},
+	{"tops", "Tops", "tops.csv"},
+}
+
+type CatalogCategory struct {
+	ID    string        `json:"id"`
+	Name  string        `json:"name"`
+	Items []CatalogItem `json:"items"`
+}
+
+type CatalogItem struct {
+	ID                 string   `json:"id"`
+	Name               string   `json:"name"`
+	DIY                bool     `json:"diy"`
+	BuyPrice           Price    `json:"buy_price"`
+	SellPrice          Price    `json:"sell_price"`
+	MilesPrice         Price    `json:"miles_price"`
+	HHABase            int      `json:"hha_base"`
+	Colors             []string `json:"colors"`
+	Variants           []string `json:"variants,omitempty"`
+	Size               string   `json:"size,omitempty"`
+	Source             string   `json:"source"`
+	SourceNotes        string   `json:"source_notes,omitempty"`
+	VillagerEquippable bool     `json:"villager_equippable"`
+	Catalog            string   `json:"catalog"`
+	Aliases            []string `json:"aliases,omitempty"`
+}
+
+type PriceState int
+
+const (
+	Priced PriceState = iota
+	// NotForSale is for things that can't be bought (or sold) at all.
+	NotForSale
+	// NotApplicable is for things where the price just doesn't apply, like
+	// the miles price of something that isn't in the Nook Miles shop.
+	NotApplicable
+)
+
+// Price is an amount of bells (or miles), or one of the explicit states for
+// when there isn't one.  It's written out as a plain number, or as the string
+// "NFS" or "NA".
+type Price struct {
+	State  PriceState
+	Amount int
+}
+
+func (p Price) MarshalJSON() ([]byte, error) {
+	switch p.State {
+	case NotForSale:
+		return json.Marshal("NFS")
+	case NotApplicable:
+		return json.Marshal("NA")
+	}
+	return json.Marshal(p.Amount)
+}
+
+func parsePrice(ps string) (Price, error) {
+	ps = strings.TrimSpace(ps)
+	switch strings.ToUpper(ps) {
+	case "NFS":
+		return Price{State: NotForSale}, nil
+	case "NA", "N/A", "":
+		return Price{State: NotApplicable}, nil
+	}
+	amount, err := strconv.Atoi(strings.ReplaceAll(ps, ",", ""))
+	if err != nil {
+		return Price{}, err
+	}
+	return Price{Amount: amount}, nil
+}
+
+func processCatalog() ([]CatalogCategory, error) {
+	var categories []CatalogCategory
+	for _, c := range catalogRegistry {
+		items, err := processCatalogFile(c.File)
+		if err != nil {
+			return categories, err
+		}
+		categories = append(categories, CatalogCategory{c.ID, c.Name, items})
+	}
+	return categories, nil
+}
+
+// processCatalogFile loads catalog items from a CSV.  Unlike the critter
+// files, columns are found by header name, so different categories can have
+// different (and differently ordered) columns.  Anything missing is left
+// empty.
+func processCatalogFile(filename string) ([]CatalogItem, error) {
+	var items []CatalogItem
+	inFile, err := os.Open(filename)
+	if err != nil {
+		return items, fmt.Errorf("unable to open catalog CSV file '%s': %w", filename, err)
+	}
+	defer inFile.Close()
+
+	r := csv.NewReader(inFile)
+	header, err := r.Read()
+	if err != nil {
+		return items, fmt.Errorf("somehow errored reading header line on '%s': %w", filename, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return items, fmt.Errorf("'%s' has no name column", filename)
+	}
+
+	for {
+		fields, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Printf("Reached end of %s.\n", filename)
+				break
+			}
+			return items, fmt.Errorf("failed reading '%s': %w", filename, err)
+		}
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		name := field("name")
+		fmt.Printf("processing %s\n", name)
+		item := CatalogItem{
+			ID:          slug(name),
+			Name:        name,
+			Size:        field("size"),
+			Source:      field("source"),
+			SourceNotes: field("source notes"),
+			Catalog:     field("catalog"),
+		}
+
+		if item.DIY, err = parseYesNo(field("diy")); err != nil {
+			return items, fmt.Errorf("DIY '%s' in '%s' was not yes or no: %w", field("diy"), name, err)
+		}
+		if item.VillagerEquippable, err = parseYesNo(field("villager equippable")); err != nil {
+			return items, fmt.Errorf("villager equippable '%s' in '%s' was not yes or no: %w", field("villager equippable"), name, err)
+		}
+		if item.BuyPrice, err = parsePrice(field("buy")); err != nil {
+			return items, fmt.Errorf("buy price '%s' in '%s' was not a valid price: %w", field("buy"), name, err)
+		}
+		if item.SellPrice, err = parsePrice(field("sell")); err != nil {
+			return items, fmt.Errorf("sell price '%s' in '%s' was not a valid price: %w", field("sell"), name, err)
+		}
+		if item.MilesPrice, err = parsePrice(field("miles price")); err != nil {
+			return items, fmt.Errorf("miles price '%s' in '%s' was not a valid price: %w", field("miles price"), name, err)
+		}
+		if hha := field("hha base points"); hha != "" {
+			if item.HHABase, err = strconv.Atoi(hha); err != nil {
+				return items, fmt.Errorf("HHA base points '%s' in '%s' was not a valid int: %w", hha, name, err)
+			}
+		}
+		for _, column := range []string{"color 1", "color 2"} {
+			color := field(column)
+			if color != "" && !containsString(item.Colors, color) {
+				item.Colors = append(item.Colors, color)
+			}
+		}
+		for _, variant := range strings.Split(field("variants"), ";") {
+			if variant = strings.TrimSpace(variant); variant != "" {
+				item.Variants = append(item.Variants, variant)
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func containsString(s []string, str string) bool {
+	for i := range s {
+		if s[i] == str {
+			return true
+		}
+	}
+	return false
+}
@@ -13,28 +13,112 @@ import (
 )
 
 type ACNH struct {
-	Bugs         []Bug         `json:"bugs"`
-	Fishes       []Fish        `json:"fishes"`
-	SeaCreatures []SeaCreature `json:"sea_creatures"`
+	Bugs         []Bug             `json:"bugs"`
+	Fishes       []Fish            `json:"fishes"`
+	SeaCreatures []SeaCreature     `json:"sea_creatures"`
+	Fossils      []Fossil          `json:"fossils"`
+	Art          []Art             `json:"art"`
+	Catalog      []CatalogCategory `json:"catalog"`
+	Villagers    []Villager        `json:"villagers"`
+	Recipes      []Recipe          `json:"recipes"`
+	Materials    []Material        `json:"materials"`
 }
 
 type Fish struct {
 	Bug
 	ShadowSize string `json:"shadow_size"`
+	Fin        bool   `json:"fin"`
 }
 
 type Bug struct {
-	Name     string `json:"name"`
-	Price    int    `json:"price"`
-	Months   []int  `json:"months"`
-	Hours    []int  `json:"hours"`
-	Location string `json:"location"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Price       int    `json:"price"`
+	Months      []int  `json:"months"`
+	Hours       []int  `json:"hours"`
+	Location    string `json:"location"`
+	Weather     string `json:"weather,omitempty"`
+	Rarity      int    `json:"rarity,omitempty"`
+	Requirement string `json:"requirement,omitempty"`
+	// Names are the critter's name in languages other than English, keyed
+	// by language code.
+	Names map[string]string `json:"names,omitempty"`
+	// Aliases are names the critter has gone by before, from aliases.csv.
+	Aliases []string `json:"aliases,omitempty"`
 }
 
 type SeaCreature struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Price       int               `json:"price"`
+	Hours       []int             `json:"hours"`
+	Months      []int             `json:"months"`
+	ShadowSize  string            `json:"shadow_size,omitempty"`
+	Speed       string            `json:"speed,omitempty"`
+	Rarity      int               `json:"rarity,omitempty"`
+	Requirement string            `json:"requirement,omitempty"`
+	Names       map[string]string `json:"names,omitempty"`
+	Aliases     []string          `json:"aliases,omitempty"`
+}
+
+// Fossil is a single fossil piece.  Multi-part fossils share a Set, and a
+// standalone fossil is its own set.
+type Fossil struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Set     string   `json:"set"`
+	Price   int      `json:"price"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Art is a piece of art Redd might sell.  HasForgery is true if Redd can also
+// sell a fake version, and Notes describes how to tell them apart.
+type Art struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Title      string   `json:"title"`
+	Artist     string   `json:"artist"`
+	Type       string   `json:"type"`
+	HasForgery bool     `json:"has_forgery"`
+	Notes      string   `json:"notes"`
+	Aliases    []string `json:"aliases,omitempty"`
+}
+
+// Villager is someone who might move to your island.  BirthdayMonth is
+// zero-based, same as critter months.
+type Villager struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Species       string `json:"species"`
+	Personality   string `json:"personality"`
+	BirthdayMonth int    `json:"birthday_month"`
+	BirthdayDay   int    `json:"birthday_day"`
+	Catchphrase   string `json:"catchphrase"`
+	Hobby         string `json:"hobby"`
+	// Aliases are names the villager has gone by before, from aliases.csv.
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Recipe is a DIY recipe.  Season-limited recipes only have the months they
+// can be picked up in, same as critters; the rest have all twelve.
+type Recipe struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Materials []Ingredient `json:"materials"`
+	Source    string       `json:"source"`
+	Months    []int        `json:"months"`
+	Aliases   []string     `json:"aliases,omitempty"`
+}
+
+type Ingredient struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+// Material is a crafting material that can only be gathered during certain
+// months.  Anything not listed is available year round.
+type Material struct {
 	Name   string `json:"name"`
-	Price  int    `json:"price"`
-	Hours  []int  `json:"hours"`
 	Months []int  `json:"months"`
 }
 
@@ -53,6 +137,33 @@ var months = map[string]int{
 	"dec": 11,
 }
 
+// daysInMonth is indexed by zero-based month, and is generous about leap
+// years.
+var daysInMonth = []int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// shadowSizes maps the lowercased first word of a shadow size column to its
+// normalized name.  Fish that show up as a long skinny shadow (eels, mostly)
+// get "Narrow".
+var shadowSizes = map[string]string{
+	"tiny":   "Tiny",
+	"small":  "Small",
+	"medium": "Medium",
+	"large":  "Large",
+	"xl":     "XL",
+	"xxl":    "XXL",
+	"narrow": "Narrow",
+	"unique": "Narrow",
+}
+
+var speeds = map[string]string{
+	"stationary": "Stationary",
+	"very slow":  "Very slow",
+	"slow":       "Slow",
+	"medium":     "Medium",
+	"fast":       "Fast",
+	"very fast":  "Very fast",
+}
+
 func main() {
 	bugs, err := processBugs()
 	if err != nil {
@@ -69,10 +180,61 @@ func main() {
 		log.Fatal(err)
 	}
 
+	fossils, err := processFossils()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	art, err := processArt()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	catalog, err := processCatalog()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	villagers, err := processVillagers()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	recipes, err := processRecipes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	materials, err := processMaterials()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	names, err := processNames("names.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := names.apply(bugs, fishes, seaCreatures); err != nil {
+		log.Fatal(err)
+	}
+
+	aliases, err := processAliases("aliases.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
 	acnh := ACNH{
 		Bugs:         bugs,
 		Fishes:       fishes,
 		SeaCreatures: seaCreatures,
+		Fossils:      fossils,
+		Art:          art,
+		Catalog:      catalog,
+		Villagers:    villagers,
+		Recipes:      recipes,
+		Materials:    materials,
+	}
+	if err := aliases.apply(&acnh); err != nil {
+		log.Fatal(err)
 	}
 
 	outFull, err := os.Create("acnh.json")
@@ -92,6 +254,8 @@ func processBugs() ([]Bug, error) {
 	defer inFile.Close()
 
 	r := csv.NewReader(inFile)
+	// Weather, rarity and requirement columns are optional.
+	r.FieldsPerRecord = -1
 	// Read off header line
 	_, err = r.Read()
 	if err != nil {
@@ -120,13 +284,25 @@ func processBugs() ([]Bug, error) {
 		if err != nil {
 			return bugs, fmt.Errorf("hours '%s' in '%s' was not a valid hour range: %w", fields[3], fields[0], err)
 		}
+		weather, err := parseWeather(optionalField(fields, 5))
+		if err != nil {
+			return bugs, fmt.Errorf("weather '%s' in '%s' was not valid: %w", optionalField(fields, 5), fields[0], err)
+		}
+		rarity, err := parseRarity(optionalField(fields, 6))
+		if err != nil {
+			return bugs, fmt.Errorf("rarity '%s' in '%s' was not valid: %w", optionalField(fields, 6), fields[0], err)
+		}
 
 		bug := Bug{
-			fields[0],
-			price,
-			months,
-			hours,
-			fields[4],
+			ID:          slug(fields[0]),
+			Name:        fields[0],
+			Price:       price,
+			Months:      months,
+			Hours:       hours,
+			Location:    fields[4],
+			Weather:     weather,
+			Rarity:      rarity,
+			Requirement: optionalField(fields, 7),
 		}
 		bugs = append(bugs, bug)
 	}
@@ -143,6 +319,8 @@ func processFish() ([]Fish, error) {
 	defer inFile.Close()
 
 	r := csv.NewReader(inFile)
+	// Weather, rarity and requirement columns are optional.
+	r.FieldsPerRecord = -1
 	// Read off header line
 	_, err = r.Read()
 	if err != nil {
@@ -172,15 +350,33 @@ func processFish() ([]Fish, error) {
 			return fishes, fmt.Errorf("hours '%s' in '%s' was not a valid hour range: %w", fields[3], fields[0], err)
 		}
 
+		shadowSize, fin, err := parseShadowSize(fields[5])
+		if err != nil {
+			return fishes, fmt.Errorf("shadow size '%s' in '%s' was not a valid shadow size: %w", fields[5], fields[0], err)
+		}
+		weather, err := parseWeather(optionalField(fields, 6))
+		if err != nil {
+			return fishes, fmt.Errorf("weather '%s' in '%s' was not valid: %w", optionalField(fields, 6), fields[0], err)
+		}
+		rarity, err := parseRarity(optionalField(fields, 7))
+		if err != nil {
+			return fishes, fmt.Errorf("rarity '%s' in '%s' was not valid: %w", optionalField(fields, 7), fields[0], err)
+		}
+
 		fish := Fish{
-			Bug{
-				fields[0],
-				price,
-				months,
-				hours,
-				fields[2],
+			Bug: Bug{
+				ID:          slug(fields[0]),
+				Name:        fields[0],
+				Price:       price,
+				Months:      months,
+				Hours:       hours,
+				Location:    fields[2],
+				Weather:     weather,
+				Rarity:      rarity,
+				Requirement: optionalField(fields, 8),
 			},
-			fields[5],
+			ShadowSize: shadowSize,
+			Fin:        fin,
 		}
 		fishes = append(fishes, fish)
 	}
@@ -197,6 +393,9 @@ func processSeaCreatures() ([]SeaCreature, error) {
 	defer inFile.Close()
 
 	r := csv.NewReader(inFile)
+	// Shadow size, movement, rarity and requirement columns are optional, so
+	// don't insist on every row having the same number of fields.
+	r.FieldsPerRecord = -1
 	// Read off header line
 	_, err = r.Read()
 	if err != nil {
@@ -226,11 +425,35 @@ func processSeaCreatures() ([]SeaCreature, error) {
 			return scs, fmt.Errorf("hours '%s' in '%s' was not a valid hour range: %w", fields[2], fields[0], err)
 		}
 
+		var shadowSize string
+		if len(fields) > 4 && strings.TrimSpace(fields[4]) != "" {
+			shadowSize, _, err = parseShadowSize(fields[4])
+			if err != nil {
+				return scs, fmt.Errorf("shadow size '%s' in '%s' was not a valid shadow size: %w", fields[4], fields[0], err)
+			}
+		}
+		var speed string
+		if len(fields) > 5 && strings.TrimSpace(fields[5]) != "" {
+			speed, err = parseSpeed(fields[5])
+			if err != nil {
+				return scs, fmt.Errorf("movement '%s' in '%s' was not a valid speed: %w", fields[5], fields[0], err)
+			}
+		}
+		rarity, err := parseRarity(optionalField(fields, 6))
+		if err != nil {
+			return scs, fmt.Errorf("rarity '%s' in '%s' was not valid: %w", optionalField(fields, 6), fields[0], err)
+		}
+
 		sc := SeaCreature{
-			fields[0],
-			price,
-			hours,
-			months,
+			ID:          slug(fields[0]),
+			Name:        fields[0],
+			Price:       price,
+			Hours:       hours,
+			Months:      months,
+			ShadowSize:  shadowSize,
+			Speed:       speed,
+			Rarity:      rarity,
+			Requirement: optionalField(fields, 7),
 		}
 		scs = append(scs, sc)
 	}
@@ -238,6 +461,335 @@ func processSeaCreatures() ([]SeaCreature, error) {
 	return scs, nil
 }
 
+func processFossils() ([]Fossil, error) {
+	var fossils []Fossil
+	inFile, err := os.Open("fossils.csv")
+	if err != nil {
+		return fossils, fmt.Errorf("unable to open fossils CSV file: %w", err)
+	}
+	defer inFile.Close()
+
+	r := csv.NewReader(inFile)
+	// Read off header line
+	_, err = r.Read()
+	if err != nil {
+		return fossils, fmt.Errorf("somehow errored reading header line on fossils input file: %w", err)
+	}
+
+	for {
+		fields, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Println("Reached end of fossils input file.")
+				break
+			}
+			return fossils, fmt.Errorf("failed reading fossils input file: %w", err)
+		}
+
+		fmt.Printf("processing %s\n", fields[0])
+		price, err := strconv.Atoi(strings.ReplaceAll(fields[2], ",", ""))
+		if err != nil {
+			return fossils, fmt.Errorf("price '%s' in '%s' was not a valid int: %w", fields[2], fields[0], err)
+		}
+		set := strings.TrimSpace(fields[1])
+		if set == "" {
+			set = fields[0]
+		}
+
+		fossils = append(fossils, Fossil{
+			ID:    slug(fields[0]),
+			Name:  fields[0],
+			Set:   set,
+			Price: price,
+		})
+	}
+
+	return fossils, nil
+}
+
+func processArt() ([]Art, error) {
+	var art []Art
+	inFile, err := os.Open("art.csv")
+	if err != nil {
+		return art, fmt.Errorf("unable to open art CSV file: %w", err)
+	}
+	defer inFile.Close()
+
+	r := csv.NewReader(inFile)
+	// Read off header line
+	_, err = r.Read()
+	if err != nil {
+		return art, fmt.Errorf("somehow errored reading header line on art input file: %w", err)
+	}
+
+	for {
+		fields, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Println("Reached end of art input file.")
+				break
+			}
+			return art, fmt.Errorf("failed reading art input file: %w", err)
+		}
+
+		fmt.Printf("processing %s\n", fields[0])
+		hasForgery, err := parseYesNo(fields[4])
+		if err != nil {
+			return art, fmt.Errorf("forgery '%s' in '%s' was not yes or no: %w", fields[4], fields[0], err)
+		}
+		if hasForgery && strings.TrimSpace(fields[5]) == "" {
+			return art, fmt.Errorf("'%s' has a forgery but no notes on how to spot it", fields[0])
+		}
+
+		art = append(art, Art{
+			ID:         slug(fields[0]),
+			Name:       fields[0],
+			Title:      fields[1],
+			Artist:     fields[2],
+			Type:       fields[3],
+			HasForgery: hasForgery,
+			Notes:      fields[5],
+		})
+	}
+
+	return art, nil
+}
+
+func processVillagers() ([]Villager, error) {
+	var villagers []Villager
+	inFile, err := os.Open("villagers.csv")
+	if err != nil {
+		return villagers, fmt.Errorf("unable to open villagers CSV file: %w", err)
+	}
+	defer inFile.Close()
+
+	r := csv.NewReader(inFile)
+	// Read off header line
+	_, err = r.Read()
+	if err != nil {
+		return villagers, fmt.Errorf("somehow errored reading header line on villagers input file: %w", err)
+	}
+
+	for {
+		fields, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Println("Reached end of villagers input file.")
+				break
+			}
+			return villagers, fmt.Errorf("failed reading villagers input file: %w", err)
+		}
+
+		fmt.Printf("processing %s\n", fields[0])
+		month, day, err := parseBirthday(fields[3])
+		if err != nil {
+			return villagers, fmt.Errorf("birthday '%s' in '%s' was not a valid date: %w", fields[3], fields[0], err)
+		}
+
+		villagers = append(villagers, Villager{
+			ID:            slug(fields[0]),
+			Name:          fields[0],
+			Species:       fields[1],
+			Personality:   fields[2],
+			BirthdayMonth: month,
+			BirthdayDay:   day,
+			Catchphrase:   fields[4],
+			Hobby:         fields[5],
+		})
+	}
+
+	return villagers, nil
+}
+
+func processRecipes() ([]Recipe, error) {
+	var recipes []Recipe
+	inFile, err := os.Open("recipes.csv")
+	if err != nil {
+		return recipes, fmt.Errorf("unable to open recipes CSV file: %w", err)
+	}
+	defer inFile.Close()
+
+	r := csv.NewReader(inFile)
+	// Read off header line
+	_, err = r.Read()
+	if err != nil {
+		return recipes, fmt.Errorf("somehow errored reading header line on recipes input file: %w", err)
+	}
+
+	for {
+		fields, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Println("Reached end of recipes input file.")
+				break
+			}
+			return recipes, fmt.Errorf("failed reading recipes input file: %w", err)
+		}
+
+		fmt.Printf("processing %s\n", fields[0])
+		materials, err := parseIngredients(fields[1])
+		if err != nil {
+			return recipes, fmt.Errorf("materials '%s' in '%s' were not valid: %w", fields[1], fields[0], err)
+		}
+		months := rng(0, 11)
+		if strings.TrimSpace(fields[3]) != "" {
+			months, err = parseMonths(fields[3])
+			if err != nil {
+				return recipes, fmt.Errorf("months '%s' in '%s' was not a valid month range: %w", fields[3], fields[0], err)
+			}
+		}
+
+		recipes = append(recipes, Recipe{
+			ID:        slug(fields[0]),
+			Name:      fields[0],
+			Materials: materials,
+			Source:    fields[2],
+			Months:    months,
+		})
+	}
+
+	return recipes, nil
+}
+
+func processMaterials() ([]Material, error) {
+	var materials []Material
+	inFile, err := os.Open("materials.csv")
+	if err != nil {
+		return materials, fmt.Errorf("unable to open materials CSV file: %w", err)
+	}
+	defer inFile.Close()
+
+	r := csv.NewReader(inFile)
+	// Read off header line
+	_, err = r.Read()
+	if err != nil {
+		return materials, fmt.Errorf("somehow errored reading header line on materials input file: %w", err)
+	}
+
+	for {
+		fields, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Println("Reached end of materials input file.")
+				break
+			}
+			return materials, fmt.Errorf("failed reading materials input file: %w", err)
+		}
+
+		fmt.Printf("processing %s\n", fields[0])
+		months, err := parseMonths(fields[1])
+		if err != nil {
+			return materials, fmt.Errorf("months '%s' in '%s' was not a valid month range: %w", fields[1], fields[0], err)
+		}
+
+		materials = append(materials, Material{
+			fields[0],
+			months,
+		})
+	}
+
+	return materials, nil
+}
+
+// parseIngredients parses a recipe's materials, which look like
+// "3 wood; 1 iron nugget".
+func parseIngredients(is string) ([]Ingredient, error) {
+	var ingredients []Ingredient
+	for _, split := range strings.Split(is, ";") {
+		split = strings.TrimSpace(split)
+		if split == "" {
+			continue
+		}
+		pair := strings.SplitN(split, " ", 2)
+		if len(pair) != 2 {
+			return ingredients, errors.New(fmt.Sprintf("material must be a quantity and a name: '%s'", split))
+		}
+		quantity, err := strconv.Atoi(pair[0])
+		if err != nil {
+			return ingredients, err
+		}
+		if quantity < 1 {
+			return ingredients, errors.New(fmt.Sprintf("quantity must be at least 1: '%s'", split))
+		}
+		ingredients = append(ingredients, Ingredient{strings.TrimSpace(pair[1]), quantity})
+	}
+	if len(ingredients) == 0 {
+		return ingredients, errors.New("recipe has no materials")
+	}
+	return ingredients, nil
+}
+
+// parseBirthday turns something like "March 10" into a zero-based month and
+// a day of the month.
+func parseBirthday(bs string) (int, int, error) {
+	splits := strings.Fields(bs)
+	if len(splits) != 2 || len(splits[0]) < 3 {
+		return 0, 0, errors.New(fmt.Sprintf("birthday must be a month and a day: '%s'", bs))
+	}
+	month, ok := months[strings.ToLower(splits[0])[:3]]
+	if !ok {
+		return 0, 0, errors.New(fmt.Sprintf("not a valid month: '%s'", splits[0]))
+	}
+	day, err := strconv.Atoi(splits[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	if day < 1 || day > daysInMonth[month] {
+		return 0, 0, errors.New(fmt.Sprintf("day out of range for %s: %d", splits[0], day))
+	}
+	return month, day, nil
+}
+
+// optionalField returns the trimmed i'th field, or "" if the row is too short
+// to have it.
+func optionalField(fields []string, i int) string {
+	if i >= len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[i])
+}
+
+// parseWeather normalizes the weather column: "Rain" for critters that only
+// show up in the rain, "No rain" for ones that hide from it, and "" for ones
+// that don't care.
+func parseWeather(ws string) (string, error) {
+	switch strings.Join(strings.Fields(strings.ToLower(ws)), " ") {
+	case "", "any":
+		return "", nil
+	case "rain", "raining", "rain only":
+		return "Rain", nil
+	case "no rain", "not raining", "clear":
+		return "No rain", nil
+	}
+	return "", errors.New(fmt.Sprintf("unknown weather: '%s'", ws))
+}
+
+// parseRarity reads a rarity from 1 (everywhere) to 5 (good luck).  Blank
+// means we don't know, and is 0.
+func parseRarity(rs string) (int, error) {
+	if rs == "" {
+		return 0, nil
+	}
+	rarity, err := strconv.Atoi(rs)
+	if err != nil {
+		return 0, err
+	}
+	if rarity < 1 || rarity > 5 {
+		return 0, errors.New(fmt.Sprintf("rarity must be between 1 and 5: %d", rarity))
+	}
+	return rarity, nil
+}
+
+func parseYesNo(s string) (bool, error) {
+	switch strings.TrimSpace(strings.ToLower(s)) {
+	case "yes", "y", "true":
+		return true, nil
+	case "no", "n", "false", "":
+		return false, nil
+	}
+	return false, errors.New(fmt.Sprintf("neither yes nor no: '%s'", s))
+}
+
 func parseMonths(ms string) ([]int, error) {
 	if strings.TrimSpace(strings.ToLower(ms)) == "all" {
 		return rng(0, 11), nil
@@ -337,6 +889,33 @@ func parseTime(ts string) (int, error) {
 	return hour % 24, nil
 }
 
+// parseShadowSize normalizes the free-form shadow size column into one of the
+// shadowSizes values, and reports whether the shadow has a dorsal fin
+// sticking out of the water (sharks and friends).
+func parseShadowSize(ss string) (string, bool, error) {
+	ss = strings.TrimSpace(strings.ToLower(ss))
+	fin := strings.Contains(ss, "fin")
+	words := strings.FieldsFunc(ss, func(r rune) bool {
+		return r == '-' || r == ' ' || r == '('
+	})
+	if len(words) == 0 {
+		return "", false, errors.New("empty shadow size")
+	}
+	normalized, ok := shadowSizes[words[0]]
+	if !ok {
+		return "", false, errors.New(fmt.Sprintf("unknown shadow size: '%s'", ss))
+	}
+	return normalized, fin, nil
+}
+
+func parseSpeed(s string) (string, error) {
+	speed, ok := speeds[strings.Join(strings.Fields(strings.ToLower(s)), " ")]
+	if !ok {
+		return "", errors.New(fmt.Sprintf("unknown movement speed: '%s'", s))
+	}
+	return speed, nil
+}
+
 func invertMonths(ms []string) []string {
 	allMonths := make(map[string]bool)
 	mons := []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
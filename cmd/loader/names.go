@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// slug turns a name into an ID that's safe in URLs, attributes and JSON
+// keys: lowercase letters and digits, with anything else between words
+// becoming a single dash.  Apostrophes just disappear, so "Rajah Brooke's
+// Birdwing" is "rajah-brookes-birdwing".
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r == '\'' || r == '’':
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// Names are critter names in other languages: category, then English name,
+// then language code.
+type Names map[string]map[string]map[string]string
+
+// processNames reads translated names from a CSV file with a Category and
+// Name column followed by one column per language, headed by its code.
+// Blank cells are left out, so anything untranslated falls back to English.
+func processNames(filename string) (Names, error) {
+	names := make(Names)
+	inFile, err := os.Open(filename)
+	if err != nil {
+		return names, fmt.Errorf("unable to open names CSV file: %w", err)
+	}
+	defer inFile.Close()
+	return readNames(inFile)
+}
+
+func readNames(in io.Reader) (Names, error) {
+	names := make(Names)
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return names, fmt.Errorf("somehow errored reading header line on names input file: %w", err)
+	}
+	if len(header) < 3 {
+		return names, errors.New("names input file needs a column for at least one language")
+	}
+	languages := header[2:]
+	for i := range languages {
+		languages[i] = strings.ToLower(strings.TrimSpace(languages[i]))
+	}
+
+	for {
+		fields, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return names, fmt.Errorf("reading names input file: %w", err)
+		}
+		if len(fields) < 2 {
+			return names, fmt.Errorf("names row %v is missing its category or name", fields)
+		}
+		category, name := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])
+		for i, lang := range languages {
+			translated := optionalField(fields, i+2)
+			if translated == "" {
+				continue
+			}
+			if names[category] == nil {
+				names[category] = make(map[string]map[string]string)
+			}
+			if names[category][name] == nil {
+				names[category][name] = make(map[string]string)
+			}
+			names[category][name][lang] = translated
+		}
+	}
+	return names, nil
+}
+
+// apply fills in the critters' Names.  A translation for a critter that
+// doesn't exist is an error, since it's almost certainly a typo in one file
+// or the other.
+func (n Names) apply(bugs []Bug, fishes []Fish, seaCreatures []SeaCreature) error {
+	used := make(map[string]bool)
+	for i := range bugs {
+		bugs[i].Names = n["bugs"][bugs[i].Name]
+		used["bugs/"+bugs[i].Name] = true
+	}
+	for i := range fishes {
+		fishes[i].Names = n["fishes"][fishes[i].Name]
+		used["fishes/"+fishes[i].Name] = true
+	}
+	for i := range seaCreatures {
+		seaCreatures[i].Names = n["sea_creatures"][seaCreatures[i].Name]
+		used["sea_creatures/"+seaCreatures[i].Name] = true
+	}
+	for category, names := range n {
+		for name := range names {
+			if !used[category+"/"+name] {
+				return fmt.Errorf("there's a translation for %s %q, but no such critter", category, name)
+			}
+		}
+	}
+	return nil
+}
@@ -1,3 +1,5 @@
 module github.com/swerveaux/acnh
 
 go 1.14
+
+require golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
@@ -0,0 +1,100 @@
+package season
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Span is a run of consecutive months or hours.  It can wrap around, so
+// November through February is {10, 4}.
+type Span struct {
+	Start  int
+	Length int
+}
+
+// Spans finds the runs of consecutive values out of 0 to n-1 that are in
+// the set.  If they all are, that's one run of n starting at 0.
+func Spans(in func(int) bool, n int) []Span {
+	first := -1
+	for i := 0; i < n; i++ {
+		if !in(i) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return []Span{{0, n}}
+	}
+	var runs []Span
+	inRun := false
+	for k := 1; k <= n; k++ {
+		i := (first + k) % n
+		if !in(i) {
+			inRun = false
+			continue
+		}
+		if !inRun {
+			runs = append(runs, Span{Start: i})
+			inRun = true
+		}
+		runs[len(runs)-1].Length++
+	}
+	return runs
+}
+
+// DisplayHour formats an hour of the day, like "4PM".
+func DisplayHour(h int) string {
+	if h == 0 {
+		return "12AM"
+	}
+	if h == 12 {
+		return "12PM"
+	}
+	if h > 12 {
+		return fmt.Sprintf("%dPM", h-12)
+	}
+	return fmt.Sprintf("%dAM", h)
+}
+
+// DescribeMonths lists the stretches of the year months covers, like
+// "November to April, June".
+func DescribeMonths(months []int) string {
+	if len(months) == 0 {
+		return "never"
+	}
+	if len(months) >= 12 {
+		return "all year"
+	}
+	var parts []string
+	for _, s := range Spans(func(m int) bool { return InMonth(months, m) }, 12) {
+		part := time.Month(s.Start + 1).String()
+		if s.Length > 1 {
+			part += " to " + time.Month((s.Start+s.Length-1)%12+1).String()
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// DescribeHours lists the stretches of the day hours covers, like
+// "4PM to 9AM".
+func DescribeHours(hours map[int]bool) string {
+	n := 0
+	for h := 0; h < 24; h++ {
+		if hours[h] {
+			n++
+		}
+	}
+	if n == 0 {
+		return "never"
+	}
+	if n == 24 {
+		return "all day"
+	}
+	var parts []string
+	for _, s := range Spans(func(h int) bool { return hours[h] }, 24) {
+		parts = append(parts, DisplayHour(s.Start)+" to "+DisplayHour((s.Start+s.Length)%24))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,41 @@
+package season
+
+import "errors"
+
+// Hemisphere decides which months critters show up in.  The data is all for
+// the northern hemisphere; the southern one is six months off.
+type Hemisphere string
+
+const (
+	Northern Hemisphere = "north"
+	Southern Hemisphere = "south"
+)
+
+var Hemispheres = []Hemisphere{Northern, Southern}
+
+func (h Hemisphere) Name() string {
+	if h == Southern {
+		return "Southern"
+	}
+	return "Northern"
+}
+
+func ParseHemisphere(s string) (Hemisphere, error) {
+	switch Hemisphere(s) {
+	case Northern, Southern:
+		return Hemisphere(s), nil
+	}
+	return Northern, errors.New("hemisphere must be north or south")
+}
+
+// Shift returns months as they are in h.
+func Shift(months []int, h Hemisphere) []int {
+	if h != Southern {
+		return months
+	}
+	shifted := make([]int, len(months))
+	for i, m := range months {
+		shifted[i] = (m + 6) % 12
+	}
+	return shifted
+}
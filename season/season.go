@@ -0,0 +1,193 @@
+// Package season works out when critters are around: which months, which
+// hours, and what that means at a given time on an island's clock.  Months
+// are 0 for January through 11 for December, and hours are 0 to 23.
+package season
+
+import (
+	"time"
+)
+
+type Timing struct {
+	AvailableNow    bool `json:"available_now"`
+	AvailableAt     int  `json:"available_at"`
+	AvailableUntil  int  `json:"available_until"`
+	AvailableAllDay bool `json:"available_all_day"`
+	CurrentHour     int  `json:"current_hour"`
+	// Status and NextMonth are only filled in by At, which knows about
+	// months as well as hours.  NextMonth is only set when the critter is
+	// out of season.
+	Status    string `json:"status,omitempty"`
+	NextMonth *int   `json:"next_month,omitempty"`
+}
+
+// Statuses a critter can have, from most to least available.
+const (
+	StatusNow         = "now"
+	StatusLaterToday  = "later_today"
+	StatusThisMonth   = "this_month"
+	StatusOutOfSeason = "out_of_season"
+)
+
+func (t *Timing) DisplayAt() string {
+	return DisplayHour(t.AvailableAt)
+}
+
+func (t *Timing) DisplayUntil() string {
+	return DisplayHour(t.AvailableUntil)
+}
+
+func (t *Timing) DisplayNextMonth() string {
+	if t.NextMonth == nil {
+		return ""
+	}
+	return time.Month(*t.NextMonth + 1).String()
+}
+
+// Display is the timing the way the critter tables show it.
+func (t *Timing) Display() string {
+	switch {
+	case t.Status == StatusOutOfSeason:
+		return "Out of season until " + t.DisplayNextMonth()
+	case t.AvailableAllDay:
+		return "All Day"
+	case t.AvailableNow:
+		return "Until " + t.DisplayUntil()
+	}
+	return "Starting at " + t.DisplayAt()
+}
+
+// HourMap turns a list of hours into a set.
+func HourMap(hours []int) map[int]bool {
+	hourMap := make(map[int]bool)
+	for i := range hours {
+		hourMap[hours[i]] = true
+	}
+	return hourMap
+}
+
+// InMonth is whether month is one of months.
+func InMonth(months []int, month int) bool {
+	for i := range months {
+		if months[i] == month {
+			return true
+		}
+	}
+	return false
+}
+
+// ForHour is the timing for hour n of the day, going by hours alone.
+func ForHour(s map[int]bool, n int) Timing {
+	if len(s) == 24 {
+		return Timing{
+			AvailableNow:    true,
+			AvailableAllDay: true,
+			CurrentHour:     n,
+		}
+	}
+
+	availableNow := s[n]
+	var availableUntil int
+	var availableAt int
+	if availableNow {
+		for i := n; i < n+24; i++ {
+			if !s[i%24] {
+				availableUntil = i % 24
+				break
+			}
+		}
+	} else {
+		for i := n; i < n+24; i++ {
+			if s[i%24] {
+				availableAt = i % 24
+				break
+			}
+		}
+	}
+
+	return Timing{
+		AvailableNow:   availableNow,
+		AvailableUntil: availableUntil,
+		AvailableAt:    availableAt,
+		CurrentHour:    n,
+	}
+}
+
+// At is ForHour for t's hour, plus whether the critter's around now, later
+// today, only at other times of day this month, or not until some other
+// month.
+func At(months []int, hours map[int]bool, t time.Time) Timing {
+	month := int(t.Month()) - 1
+	at := ForHour(hours, t.Hour())
+	switch {
+	case !InMonth(months, month):
+		at.AvailableNow = false
+		at.AvailableAllDay = false
+		at.Status = StatusOutOfSeason
+		for i := 1; i <= 12; i++ {
+			if next := (month + i) % 12; InMonth(months, next) {
+				at.NextMonth = &next
+				break
+			}
+		}
+	case at.AvailableNow:
+		at.Status = StatusNow
+	case at.AvailableAt > t.Hour():
+		at.Status = StatusLaterToday
+	default:
+		at.Status = StatusThisMonth
+	}
+	return at
+}
+
+// Around is whether the critter's out during t's hour.
+func Around(months []int, hours map[int]bool, t time.Time) bool {
+	return InMonth(months, int(t.Month())-1) && hours[t.Hour()]
+}
+
+// Leaving is whether the critter's around in month but not the one after.
+func Leaving(months []int, month int) bool {
+	return InMonth(months, month) && !InMonth(months, (month+1)%12)
+}
+
+// Window is a stretch of time a critter's around, to the hour.  End is
+// missing for critters that never leave.
+type Window struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+func (w Window) Display() string {
+	if w.End == nil {
+		return "From " + w.Start.Format("Mon Jan 2 3PM") + ", all year"
+	}
+	return w.Start.Format("Mon Jan 2 3PM") + " to " + w.End.Format("Mon Jan 2 3PM")
+}
+
+// maxWindowSearch is how far ahead Next looks.
+const maxWindowSearch = 366 * 24
+
+// Next finds the next stretch of hours the critter's around, starting with
+// the one it's in now if it's out.  t should be in the island's time zone.
+// It's nil if the critter won't be around in the next year.
+func Next(months []int, hours map[int]bool, t time.Time) *Window {
+	hour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	for i := 0; i < maxWindowSearch; i++ {
+		start := hour.Add(time.Duration(i) * time.Hour)
+		if !Around(months, hours, start) {
+			continue
+		}
+		w := &Window{Start: start}
+		if i == 0 {
+			w.Start = t
+		}
+		end := start
+		for j := 0; j < maxWindowSearch; j++ {
+			if end = end.Add(time.Hour); !Around(months, hours, end) {
+				w.End = &end
+				break
+			}
+		}
+		return w
+	}
+	return nil
+}
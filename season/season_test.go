@@ -0,0 +1,107 @@
+package season
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAvailability(t *testing.T) {
+	evenings := map[int]bool{17: true, 18: true, 19: true}
+	tests := []struct {
+		Name      string
+		Months    []int
+		Hour      int
+		Status    string
+		NextMonth int
+	}{
+		{"now", []int{6}, 18, StatusNow, -1},
+		{"later today", []int{6}, 9, StatusLaterToday, -1},
+		{"this month", []int{6}, 21, StatusThisMonth, -1},
+		{"out of season", []int{2, 3, 9}, 18, StatusOutOfSeason, 9},
+		{"out of season until next year", []int{0, 1}, 18, StatusOutOfSeason, 0},
+	}
+
+	for _, test := range tests {
+		got := At(test.Months, evenings, time.Date(2020, time.July, 1, test.Hour, 0, 0, 0, time.UTC))
+		if got.Status != test.Status {
+			t.Errorf("Failed test '%s': expected status %s, got %s", test.Name, test.Status, got.Status)
+		}
+		if test.NextMonth < 0 && got.NextMonth != nil {
+			t.Errorf("Failed test '%s': expected no next month, got %d", test.Name, *got.NextMonth)
+		}
+		if test.NextMonth >= 0 && (got.NextMonth == nil || *got.NextMonth != test.NextMonth) {
+			t.Errorf("Failed test '%s': expected next month %d, got %v", test.Name, test.NextMonth, got.NextMonth)
+		}
+		if test.Status == StatusOutOfSeason && got.AvailableNow {
+			t.Errorf("Failed test '%s': out of season critters shouldn't be available now", test.Name)
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	set := func(values ...int) func(int) bool {
+		return func(i int) bool { return InMonth(values, i) }
+	}
+	tests := []struct {
+		values []int
+		n      int
+		want   []Span
+	}{
+		{[]int{3, 4, 5}, 12, []Span{{3, 3}}},
+		{[]int{0, 1, 10, 11}, 12, []Span{{10, 4}}},
+		{[]int{1, 2, 6}, 12, []Span{{1, 2}, {6, 1}}},
+		{[]int{0, 1, 2}, 3, []Span{{0, 3}}},
+		{nil, 12, nil},
+	}
+	for _, tt := range tests {
+		if got := Spans(set(tt.values...), tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Spans(%v, %d) = %v, want %v", tt.values, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	nights := HourMap([]int{19, 20, 21, 22, 23, 0, 1, 2, 3})
+	winter := []int{0, 1, 2, 3, 10, 11}
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2020, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		Now        time.Time
+		Start, End time.Time
+	}{
+		{at(time.January, 5, 21, 30), at(time.January, 5, 21, 30), at(time.January, 6, 4, 0)},
+		{at(time.January, 5, 12, 0), at(time.January, 5, 19, 0), at(time.January, 6, 4, 0)},
+		{at(time.May, 1, 12, 0), at(time.November, 1, 0, 0), at(time.November, 1, 4, 0)},
+		{at(time.April, 30, 22, 0), at(time.April, 30, 22, 0), at(time.May, 1, 0, 0)},
+	}
+	for _, test := range tests {
+		w := Next(winter, nights, test.Now)
+		if w == nil || !w.Start.Equal(test.Start) || w.End == nil || !w.End.Equal(test.End) {
+			t.Errorf("at %v expected %v to %v, got %+v", test.Now, test.Start, test.End, w)
+		}
+	}
+
+	allYear := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	allDay := HourMap([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23})
+	if w := Next(allYear, allDay, at(time.May, 1, 12, 0)); w == nil || w.End != nil {
+		t.Errorf("expected a critter that's always around to never leave, got %+v", w)
+	}
+	if w := Next(nil, nights, at(time.May, 1, 12, 0)); w != nil {
+		t.Errorf("expected no window for a critter that's never around, got %+v", w)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	if got := DescribeMonths([]int{0, 1, 5, 10, 11}); got != "June, November to February" {
+		t.Errorf("unexpected months %q", got)
+	}
+	if got := DescribeHours(HourMap([]int{16, 17, 18, 19, 20, 21, 22, 23, 0, 1, 2, 3, 4, 5, 6, 7, 8})); got != "4PM to 9AM" {
+		t.Errorf("unexpected hours %q", got)
+	}
+	if got := DisplayHour(0) + " " + DisplayHour(12) + " " + DisplayHour(13); got != "12AM 12PM 1PM" {
+		t.Errorf("unexpected hours %q", got)
+	}
+}